@@ -0,0 +1,79 @@
+package distributions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+const (
+	ALMA_MIRRORS_URL     = "https://mirrors.almalinux.org/mirrorlist/%v/baseos"
+	ALMA_DEFAULT_RELEASE = "10"
+	ALMA_DEFAULT_ARCH    = "x86_64"
+)
+
+type AlmaLinux struct {
+	// Release version (e.g. "9", "9.4") of the repositories
+	Version string
+	// Base architecture (e.g. "x86_64", "aarch64") of the repositories
+	Arch string
+}
+
+func (alma AlmaLinux) Name() string { return "AlmaLinux" }
+
+func (alma AlmaLinux) WithRelease(release string) Distributor {
+	alma.Version = release
+	return alma
+}
+
+func (alma AlmaLinux) Release() string {
+	if len(alma.Version) == 0 {
+		return ALMA_DEFAULT_RELEASE
+	}
+	return alma.Version
+}
+
+func (alma AlmaLinux) WithArchitecture(arch string) Distributor {
+	alma.Arch = arch
+	return alma
+}
+
+func (alma AlmaLinux) Architecture() string {
+	if len(alma.Arch) == 0 {
+		return ALMA_DEFAULT_ARCH
+	}
+	return alma.Arch
+}
+
+func (alma AlmaLinux) Repositories() []string { return []string{"BaseOS", "AppStream"} }
+
+func (alma AlmaLinux) GPGKey() string {
+	return "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-AlmaLinux-" + majorVersion(alma.Release())
+}
+
+func (alma AlmaLinux) RepoFile() string { return "almalinux" }
+
+func (alma AlmaLinux) Canary() mirrors.Canary { return yumCanary(alma) }
+
+// URL of the mirror list of the BaseOS repository (same for all architectures, with a "$basearch" variable)
+func (alma AlmaLinux) MirrorlistURL() string {
+	return fmt.Sprintf(ALMA_MIRRORS_URL, alma.Release())
+}
+
+func (alma AlmaLinux) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return alma.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (alma AlmaLinux) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		return fetchYumMirrorlistContext(ctx, alma.MirrorlistURL(), "BaseOS", alma.Architecture())
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
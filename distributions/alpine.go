@@ -0,0 +1,222 @@
+package distributions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ALPINE_MIRRORS_URL     = "https://mirrors.alpinelinux.org/mirrors.yaml"
+	ALPINE_MIRRORS_TXT_URL = "https://mirrors.alpinelinux.org/mirrors.txt"
+	ALPINE_DEFAULT_BRANCH  = "latest-stable"
+)
+
+type Alpine struct {
+	// Branch ("latest-stable", "edge" or a version like "v3.20") used for the repositories
+	Branch string
+}
+
+// Mirror entry of mirrors.yaml
+type alpineMirror struct {
+	Name      string   `yaml:"name"`
+	Location  string   `yaml:"location"`
+	Country   string   `yaml:"country"`
+	Bandwidth string   `yaml:"bandwidth"`
+	URLs      []string `yaml:"urls"`
+}
+
+func (alp Alpine) Name() string { return "Alpine" }
+
+func (alp Alpine) WithRelease(release string) Distributor {
+	alp.Branch = release
+	return alp
+}
+
+// Returns the branch of the release, versions are prefixed with "v" (e.g. "3.20" is "v3.20")
+func (alp Alpine) Release() string {
+	if len(alp.Branch) == 0 {
+		return ALPINE_DEFAULT_BRANCH
+	}
+	if alp.Branch[0] >= '0' && alp.Branch[0] <= '9' {
+		return "v" + alp.Branch
+	}
+	return alp.Branch
+}
+
+// Repositories enabled by default
+func (alp Alpine) Repositories() []string { return []string{"main", "community"} }
+
+// Index of the main repository of the branch (a few MB)
+func (alp Alpine) SpeedTestPath() string { return alp.Release() + "/main/x86_64/APKINDEX.tar.gz" }
+
+// Package index of the main repository of the branch
+func (alp Alpine) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: alp.Release() + "/main/x86_64/APKINDEX.tar.gz", Signature: mirrors.SIGNATURE_GZIP}
+}
+
+func (alp Alpine) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return alp.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (alp Alpine) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		mirrorsList, err := FetchAlpineMirrorsContext(ctx, ALPINE_MIRRORS_URL)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error: Failed to fetch mirrors.yaml, falling back to mirrors.txt:", err)
+			return FetchAlpineMirrorsTXTContext(ctx, ALPINE_MIRRORS_TXT_URL)
+		}
+		return mirrorsList, err
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
+
+func FetchAlpineMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchAlpineMirrorsContext(context.Background(), URL)
+}
+
+// Same as FetchAlpineMirrors, but the request is aborted when the context is done.
+func FetchAlpineMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	// Make request
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	// Parse YAML mirror list
+	var entries []alpineMirror
+	if err := yaml.Unmarshal(resp, &entries); err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, entry := range entries {
+		// Location is either a country or ends with one (e.g. "Amsterdam, Netherlands")
+		countryCode := strings.ToUpper(entry.Country)
+		country := utils.GetCountryName(countryCode)
+		if len(country) == 0 {
+			parts := strings.Split(entry.Location, ",")
+			country = strings.TrimSpace(parts[len(parts)-1])
+			countryCode = utils.GetCountryCode(country)
+		}
+		// One mirror for each protocol
+		for _, link := range entry.URLs {
+			mirror, err := alpineMirrorURL(link)
+			if err != nil {
+				log.Println("Error: Failed to parse URL:", err)
+				continue
+			}
+			mirror.Country = country
+			mirror.CountryCode = countryCode
+			mirror.Bandwidth = entry.Bandwidth
+			mirrorsList = append(mirrorsList, mirror)
+		}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+func FetchAlpineMirrorsTXT(URL string) ([]mirrors.Mirror, error) {
+	return FetchAlpineMirrorsTXTContext(context.Background(), URL)
+}
+
+// Same as FetchAlpineMirrorsTXT, but the request is aborted when the context is done.
+func FetchAlpineMirrorsTXTContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	// Make request
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	// Parse mirror URLs (one per line)
+	mirrorsList := []mirrors.Mirror{}
+	scanner := bufio.NewScanner(bytes.NewReader(resp))
+	for scanner.Scan() {
+		link := strings.TrimSpace(scanner.Text())
+		if len(link) == 0 || strings.HasPrefix(link, "#") {
+			continue
+		}
+		mirror, err := alpineMirrorURL(link)
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Creates a mirror from its URL
+func alpineMirrorURL(link string) (mirrors.Mirror, error) {
+	urlStr, err := url.Parse(link)
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	proto, err := mirrors.ToProtocol(urlStr.Scheme)
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	return mirrors.Mirror{URL: urlStr, Protocol: proto}, nil
+}
+
+// Options for apk repositories output
+type ApkOptions struct {
+	// Repositories of the branch (default: main and community)
+	Repositories []string
+	// Number of mirrors to use (default: only the best)
+	Top int
+}
+
+// Writes the best mirrors as apk repositories (/etc/apk/repositories)
+func (d *DistributionMirrors) WriteApkRepositories(w io.Writer, opts ApkOptions) error {
+	distro, ok := d.Distribution.(Alpine)
+	if !ok {
+		return fmt.Errorf("apk repositories are not supported for %v", d.Distribution.Name())
+	}
+	if len(opts.Repositories) == 0 {
+		opts.Repositories = distro.Repositories()
+	}
+	if opts.Top <= 0 {
+		opts.Top = 1
+	}
+	best := d.usableMirrors(opts.Top, "http", "https")
+	if len(best) == 0 {
+		return errors.New("no reachable HTTP mirror")
+	}
+	if _, err := fmt.Fprintf(w, "# %v %v - generated by gomirror\n", distro.Name(), distro.Release()); err != nil {
+		return err
+	}
+	for _, mirror := range best {
+		base := mirror.URL.String()
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		for _, repo := range opts.Repositories {
+			if _, err := fmt.Fprintf(w, "%v%v/%v\n", base, distro.Release(), repo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
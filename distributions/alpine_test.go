@@ -0,0 +1,78 @@
+package distributions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchAlpineMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/alpine.yaml")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchAlpineMirrors(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 6)
+	assert.Equal(t, "http://dl-cdn.alpinelinux.org/alpine/", mirrorsList[0].URL.String())
+	assert.Equal(t, "10 Gbps", mirrorsList[0].Bandwidth)
+	assert.Equal(t, "", mirrorsList[0].CountryCode)
+	assert.Equal(t, mirrors.ProtoHTTPS, mirrorsList[1].Protocol)
+	assert.Equal(t, "DE", mirrorsList[2].CountryCode)
+	assert.Equal(t, "Germany", mirrorsList[2].Country)
+	assert.Equal(t, mirrors.ProtoRSYNC, mirrorsList[4].Protocol)
+	assert.Equal(t, "GR", mirrorsList[5].CountryCode)
+	assert.Equal(t, "1 Gbps", mirrorsList[5].Bandwidth)
+}
+
+func TestFetchAlpineMirrorsTXT(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/alpine.txt")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchAlpineMirrorsTXT(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 3)
+	assert.Equal(t, "https://ftp.halifax.rwth-aachen.de/alpine/", mirrorsList[1].URL.String())
+	assert.Equal(t, mirrors.ProtoHTTPS, mirrorsList[1].Protocol)
+}
+
+func TestAlpineSpeedTestPath(t *testing.T) {
+	assert.Equal(t, "latest-stable/main/x86_64/APKINDEX.tar.gz", Alpine{}.SpeedTestPath())
+	assert.Equal(t, "edge/main/x86_64/APKINDEX.tar.gz", Alpine{Branch: "edge"}.SpeedTestPath())
+}
+
+func TestWriteApkRepositories(t *testing.T) {
+	newMirror := func(rawURL string, avg time.Duration) *mirrors.Mirror {
+		u, _ := url.Parse(rawURL)
+		return &mirrors.Mirror{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: avg}}
+	}
+	d := &DistributionMirrors{Distribution: Alpine{}.WithRelease("3.20"), Mirrors: []*mirrors.Mirror{
+		newMirror("rsync://ftp.halifax.rwth-aachen.de/alpine/", time.Millisecond),
+		newMirror("http://ftp.cc.uoc.gr/mirrors/linux/alpine", 2*time.Millisecond),
+		newMirror("https://dl-cdn.alpinelinux.org/alpine/", 3*time.Millisecond),
+	}}
+	expected := `# Alpine v3.20 - generated by gomirror
+http://ftp.cc.uoc.gr/mirrors/linux/alpine/v3.20/main
+http://ftp.cc.uoc.gr/mirrors/linux/alpine/v3.20/community
+`
+	var b strings.Builder
+	assert.NoError(t, d.WriteApkRepositories(&b, ApkOptions{}))
+	assert.Equal(t, expected, b.String())
+
+	d.Distribution = Alpine{}.WithRelease("edge")
+	b.Reset()
+	assert.NoError(t, d.WriteApkRepositories(&b, ApkOptions{Repositories: []string{"main", "testing"}, Top: 2}))
+	assert.Contains(t, b.String(), "https://dl-cdn.alpinelinux.org/alpine/edge/testing\n")
+
+	d.Distribution = Arch{}
+	assert.Error(t, d.WriteApkRepositories(&b, ApkOptions{}))
+}
@@ -0,0 +1,180 @@
+package distributions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Implemented by distributions that use the APT repository layout (Debian, Ubuntu)
+type AptDistributor interface {
+	Releaser
+	// URL of the upstream archive, used as a reference for the freshness of mirrors
+	UpstreamURL() string
+	// Components enabled by default (e.g. "main")
+	Components() []string
+	// Keyring used to verify the archive
+	Keyring() string
+	// URL and suite of the security updates of a suite (empty if there are none).
+	// Security updates are not taken from mirrors, since they are not always synced in time.
+	SecuritySource(suite string) (URL string, securitySuite string)
+}
+
+// Path of the Release file of a suite, relative to the mirror URL
+func ReleasePath(suite string) string {
+	return "dists/" + suite + "/Release"
+}
+
+// Path of the signed Release file of a suite, relative to the mirror URL
+func InReleasePath(suite string) string {
+	return "dists/" + suite + "/InRelease"
+}
+
+// Options for APT sources outputs
+type AptOptions struct {
+	// Suites of the mirrors (default: release and its updates suite).
+	// The security updates of the releases of the suites are added from the security archive.
+	Suites []string
+	// Components of the suites (default: components of the distribution)
+	Components []string
+	// Include source packages (deb-src)
+	Source bool
+	// Number of mirrors to use (default: only the best)
+	Top int
+}
+
+// Fills in the default options of the distribution
+func (opts AptOptions) withDefaults(distro AptDistributor) AptOptions {
+	if len(opts.Suites) == 0 {
+		opts.Suites = []string{distro.Release(), distro.Release() + "-updates"}
+	}
+	if len(opts.Components) == 0 {
+		opts.Components = distro.Components()
+	}
+	if opts.Top <= 0 {
+		opts.Top = 1
+	}
+	return opts
+}
+
+// Source of packages, as written in APT sources
+type aptSource struct {
+	URIs   []string
+	Suites []string
+}
+
+// Returns the sources of the best mirrors and the security updates
+func (d *DistributionMirrors) aptSources(opts AptOptions) (AptDistributor, AptOptions, []aptSource, error) {
+	distro, ok := d.Distribution.(AptDistributor)
+	if !ok {
+		return nil, opts, nil, fmt.Errorf("APT sources are not supported for %v", d.Distribution.Name())
+	}
+	opts = opts.withDefaults(distro)
+	best := d.usableMirrors(opts.Top, "http", "https")
+	if len(best) == 0 {
+		return nil, opts, nil, errors.New("no reachable HTTP mirror")
+	}
+	uris := make([]string, len(best))
+	for i, mirror := range best {
+		uris[i] = mirror.URL.String()
+	}
+	sources := []aptSource{{URIs: uris, Suites: opts.Suites}}
+	// Security updates of the releases of the suites, grouped by URL
+	securitySuites := map[string][]string{}
+	securityURLs := []string{}
+	seen := map[string]bool{}
+	for _, suite := range opts.Suites {
+		base := baseSuite(suite)
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		securityURL, securitySuite := distro.SecuritySource(base)
+		if len(securityURL) == 0 {
+			continue
+		}
+		if _, found := securitySuites[securityURL]; !found {
+			securityURLs = append(securityURLs, securityURL)
+		}
+		securitySuites[securityURL] = append(securitySuites[securityURL], securitySuite)
+	}
+	for _, securityURL := range securityURLs {
+		sources = append(sources, aptSource{URIs: []string{securityURL}, Suites: securitySuites[securityURL]})
+	}
+	return distro, opts, sources, nil
+}
+
+// Release suite of a suite (e.g. "bookworm" for "bookworm-updates" or "bookworm/updates")
+func baseSuite(suite string) string {
+	if base, _, found := strings.Cut(suite, "/"); found {
+		return base
+	}
+	for _, pocket := range []string{"-proposed-updates", "-backports-sloppy", "-updates", "-security", "-backports", "-proposed"} {
+		if strings.HasSuffix(suite, pocket) {
+			return strings.TrimSuffix(suite, pocket)
+		}
+	}
+	return suite
+}
+
+// Writes the best mirrors as APT sources in the classic one-line format (/etc/apt/sources.list)
+func (d *DistributionMirrors) WriteSourcesList(w io.Writer, opts AptOptions) error {
+	distro, opts, sources, err := d.aptSources(opts)
+	if err != nil {
+		return err
+	}
+	types := []string{"deb"}
+	if opts.Source {
+		types = append(types, "deb-src")
+	}
+	components := strings.Join(opts.Components, " ")
+	if _, err := fmt.Fprintf(w, "# %v %v - generated by gomirror\n", distro.Name(), distro.Release()); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		for _, suite := range source.Suites {
+			for _, uri := range source.URIs {
+				for _, t := range types {
+					if _, err := fmt.Fprintf(w, "%v %v %v %v\n", t, uri, suite, components); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Writes the best mirrors as APT sources in the deb822 format (/etc/apt/sources.list.d/*.sources)
+func (d *DistributionMirrors) WriteDeb822(w io.Writer, opts AptOptions) error {
+	distro, opts, sources, err := d.aptSources(opts)
+	if err != nil {
+		return err
+	}
+	types := []string{"deb"}
+	if opts.Source {
+		types = append(types, "deb-src")
+	}
+	if _, err := fmt.Fprintf(w, "# %v %v - generated by gomirror\n", distro.Name(), distro.Release()); err != nil {
+		return err
+	}
+	for i, source := range sources {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		stanza := fmt.Sprintf("Types: %v\nURIs: %v\nSuites: %v\nComponents: %v\nSigned-By: %v\n",
+			strings.Join(types, " "),
+			strings.Join(source.URIs, " "),
+			strings.Join(source.Suites, " "),
+			strings.Join(opts.Components, " "),
+			distro.Keyring(),
+		)
+		if _, err := io.WriteString(w, stanza); err != nil {
+			return err
+		}
+	}
+	return nil
+}
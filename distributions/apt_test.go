@@ -0,0 +1,89 @@
+package distributions
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func newAptTestMirrors(distro Distributor) *DistributionMirrors {
+	newMirror := func(rawURL string, latency time.Duration) *mirrors.Mirror {
+		u, _ := url.Parse(rawURL)
+		return &mirrors.Mirror{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: latency}}
+	}
+	return &DistributionMirrors{Distribution: distro, Mirrors: []*mirrors.Mirror{
+		newMirror("ftp://ftp.example.org/debian/", 5*time.Millisecond),
+		unreachable(newMirror("http://down.example.org/debian/", 0)),
+		newMirror("http://ftp.gr.debian.org/debian/", 10*time.Millisecond),
+		newMirror("https://mirror.example.com/debian/", 20*time.Millisecond),
+	}}
+}
+
+func TestWriteSourcesList(t *testing.T) {
+	d := newAptTestMirrors(Debian{Suite: "bookworm"})
+	expected := `# Debian bookworm - generated by gomirror
+deb http://ftp.gr.debian.org/debian/ bookworm main
+deb-src http://ftp.gr.debian.org/debian/ bookworm main
+deb http://ftp.gr.debian.org/debian/ bookworm-updates main
+deb-src http://ftp.gr.debian.org/debian/ bookworm-updates main
+deb http://security.debian.org/debian-security/ bookworm-security main
+deb-src http://security.debian.org/debian-security/ bookworm-security main
+`
+	var b strings.Builder
+	err := d.WriteSourcesList(&b, AptOptions{Source: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+
+	// Older releases use a different security suite
+	d.Distribution = Debian{Suite: "buster"}
+	b.Reset()
+	err = d.WriteSourcesList(&b, AptOptions{Suites: []string{"buster"}, Components: []string{"main", "contrib"}})
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "deb http://security.debian.org/debian-security/ buster/updates main contrib\n")
+}
+
+func TestWriteSourcesListSuites(t *testing.T) {
+	d := newAptTestMirrors(Debian{Suite: "bookworm"})
+	expected := `# Debian bookworm - generated by gomirror
+deb http://ftp.gr.debian.org/debian/ trixie main
+deb http://ftp.gr.debian.org/debian/ trixie-updates main
+deb http://ftp.gr.debian.org/debian/ bullseye-backports main
+deb http://ftp.gr.debian.org/debian/ sid main
+deb http://security.debian.org/debian-security/ trixie-security main
+deb http://security.debian.org/debian-security/ bullseye-security main
+`
+	// The security suites follow the requested suites, not the release
+	var b strings.Builder
+	err := d.WriteSourcesList(&b, AptOptions{Suites: []string{"trixie", "trixie-updates", "bullseye-backports", "sid"}})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+}
+
+func TestWriteDeb822(t *testing.T) {
+	d := newAptTestMirrors(Ubuntu{Suite: "noble"})
+	expected := `# Ubuntu noble - generated by gomirror
+Types: deb
+URIs: http://ftp.gr.debian.org/debian/ https://mirror.example.com/debian/
+Suites: noble noble-updates
+Components: main restricted universe multiverse
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+
+Types: deb
+URIs: http://security.ubuntu.com/ubuntu/
+Suites: noble-security
+Components: main restricted universe multiverse
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+`
+	var b strings.Builder
+	err := d.WriteDeb822(&b, AptOptions{Top: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+
+	// Only APT distributions are supported
+	d.Distribution = Arch{}
+	assert.Error(t, d.WriteDeb822(&b, AptOptions{}))
+}
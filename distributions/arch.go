@@ -1,9 +1,13 @@
 package distributions
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/url"
+	"time"
 
 	"github.com/thanoskoutr/gomirror/mirrors"
 	"github.com/thanoskoutr/gomirror/utils"
@@ -13,56 +17,135 @@ const (
 	ARCH_MIRRORS_URL = "https://archlinux.org/mirrors/status/json/"
 )
 
-type Arch struct{}
+type Arch struct {
+	// Include mirrors reported as inactive or not fully synced by the mirror status
+	IncludeInactive bool
+}
+
+// Mirror entry of the Arch mirror status JSON
+type archMirrorStatus struct {
+	URL           string     `json:"url"`
+	Protocol      string     `json:"protocol"`
+	LastSync      *time.Time `json:"last_sync"`
+	CompletionPct float64    `json:"completion_pct"`
+	Delay         *int64     `json:"delay"`
+	Score         *float64   `json:"score"`
+	Active        bool       `json:"active"`
+	Country       string     `json:"country"`
+	CountryCode   string     `json:"country_code"`
+	ISOs          bool       `json:"isos"`
+	IPv4          bool       `json:"ipv4"`
+	IPv6          bool       `json:"ipv6"`
+}
 
 func (arch Arch) Name() string { return "Arch" }
 
-func (arch Arch) GetMirrors(source mirrors.MirrorSource, filename string) []mirrors.Mirror {
+// Package database of the core repository
+func (arch Arch) SpeedTestPath() string { return "core/os/x86_64/core.db" }
+
+// Package database of the core repository (gzip compressed tarball)
+func (arch Arch) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: "core/os/x86_64/core.db", Signature: mirrors.SIGNATURE_GZIP}
+}
+
+func (arch Arch) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return arch.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (arch Arch) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
 	switch source {
 	case mirrors.SourceHTTP:
-		// TODO: If error online fallback to internal mirrors
-		return FetchArchMirrors(ARCH_MIRRORS_URL)
+		mirrorsList, err := FetchArchMirrorsContext(ctx, ARCH_MIRRORS_URL)
+		if err != nil || arch.IncludeInactive {
+			return mirrorsList, err
+		}
+		mirrorsList = ActiveArchMirrors(mirrorsList)
+		if len(mirrorsList) == 0 {
+			return nil, mirrors.ErrNoMirrors
+		}
+		return mirrorsList, nil
 	case mirrors.SourceJSON:
 		return mirrors.ReadMirrorsJSON(filename)
 	case mirrors.SourceTXT:
 		return mirrors.ReadMirrorsTXT(filename)
 	default:
-		return []mirrors.Mirror{}
+		return nil, fmt.Errorf("unsupported source type: %v", source)
 	}
 }
 
-// TODO: Error handling
-// TODO: Add error checking for JSON fields
-// TODO: Take note of other JSON attributes for mirrors: delay, score, active
-func FetchArchMirrors(URL string) []mirrors.Mirror {
+// Returns the mirrors that are active and fully synced, according to the mirror status
+func ActiveArchMirrors(mirrorsList []mirrors.Mirror) []mirrors.Mirror {
+	active := []mirrors.Mirror{}
+	for _, mirror := range mirrorsList {
+		if mirror.Active && mirror.CompletionPct >= 1 {
+			active = append(active, mirror)
+		}
+	}
+	return active
+}
+
+func FetchArchMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchArchMirrorsContext(context.Background(), URL)
+}
+
+// Same as FetchArchMirrors, but the request is aborted when the context is done.
+func FetchArchMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
 	// Make request
-	resp, err := utils.GetRequest(URL)
+	resp, err := utils.GetRequestContext(ctx, URL)
 	if err != nil {
-		log.Fatal("Can not parse Arch mirrors list: ", err)
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
 	}
 	// Parse response as JSON
-	respJson := map[string]interface{}{}
+	respJson := struct {
+		URLs []archMirrorStatus `json:"urls"`
+	}{}
 	err = json.Unmarshal(resp, &respJson)
 	if err != nil {
-		log.Fatal("Can not parse Arch mirrors list: ", err)
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if respJson.URLs == nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing \"urls\" array")}
 	}
-	// Get URLs array
-	urls := respJson["urls"].([]interface{})
 	// Create Mirrors array
-	mirrorsList := make([]mirrors.Mirror, len(urls))
+	mirrorsList := make([]mirrors.Mirror, 0, len(respJson.URLs))
 	// Convert URLs object to Mirrors
-	for i, u := range urls {
-		urlMap := u.(map[string]interface{})
+	for _, status := range respJson.URLs {
 		// Parse URL
-		urlStr, err := url.Parse(urlMap["url"].(string))
+		urlStr, err := url.Parse(status.URL)
 		if err != nil {
 			log.Println("Error: Failed to parse URL: ", err)
+			continue
 		}
-		mirrorsList[i] = mirrors.Mirror{
-			Country:     urlMap["country"].(string),
-			CountryCode: urlMap["country_code"].(string),
-			URL:         urlStr,
+		protocol, err := mirrors.ToProtocol(status.Protocol)
+		if err != nil {
+			log.Println("Error: Failed to parse protocol: ", err)
+			continue
 		}
+		mirror := mirrors.Mirror{
+			Country:       status.Country,
+			CountryCode:   status.CountryCode,
+			URL:           urlStr,
+			Protocol:      protocol,
+			CompletionPct: status.CompletionPct,
+			Active:        status.Active,
+			ISOs:          status.ISOs,
+			IPv4:          status.IPv4,
+			IPv6:          status.IPv6,
+		}
+		// Mirrors that never synced have no status
+		if status.LastSync != nil {
+			mirror.LastSync = *status.LastSync
+		}
+		if status.Delay != nil {
+			mirror.Delay = time.Duration(*status.Delay) * time.Second
+		}
+		if status.Score != nil {
+			mirror.Score = *status.Score
+		}
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
 	}
-	return mirrorsList
+	return mirrorsList, nil
 }
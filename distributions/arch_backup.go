@@ -1,3 +0,0 @@
-package distributions
-
-var ArchBackupMirrors map[string]interface{}
@@ -0,0 +1,64 @@
+package distributions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchArchMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../inputs/arch.json")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchArchMirrors(server.URL)
+	assert.NoError(t, err)
+	first := mirrorsList[0]
+	assert.Equal(t, "https://mirror.aarnet.edu.au/pub/archlinux/", first.URL.String())
+	assert.Equal(t, mirrors.ProtoHTTPS, first.Protocol)
+	assert.Equal(t, "AU", first.CountryCode)
+	assert.Equal(t, time.Date(2022, time.October, 1, 21, 30, 7, 0, time.UTC), first.LastSync)
+	assert.Equal(t, 1935*time.Second, first.Delay)
+	assert.InDelta(t, 2.516, first.Score, 0.001)
+	assert.True(t, first.Active && first.ISOs && first.IPv4 && first.IPv6)
+
+	active := ActiveArchMirrors(mirrorsList)
+	assert.NotEmpty(t, active)
+	assert.Less(t, len(active), len(mirrorsList))
+	for _, m := range active {
+		assert.True(t, m.Active)
+		assert.Equal(t, 1.0, m.CompletionPct)
+	}
+}
+
+func TestUpdateRankScores(t *testing.T) {
+	newMirror := func(host string, score float64, latency time.Duration) *mirrors.Mirror {
+		return &mirrors.Mirror{
+			URL:        &url.URL{Scheme: "https", Host: host},
+			Score:      score,
+			Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: latency},
+		}
+	}
+	d := &DistributionMirrors{Mirrors: []*mirrors.Mirror{
+		newMirror("fast-low-score", 4, 10*time.Millisecond),
+		newMirror("slow-high-score", 1, 30*time.Millisecond),
+		unreachable(newMirror("unreachable", 1, 0)),
+	}}
+
+	d.UpdateRankScores(0)
+	d.SortMirrorsBy(SortScore)
+	assert.Equal(t, "fast-low-score", d.Mirrors[0].URL.Host)
+	assert.Equal(t, "unreachable", d.Mirrors[2].URL.Host)
+
+	d.UpdateRankScores(1)
+	d.SortMirrorsBy(SortScore)
+	assert.Equal(t, "slow-high-score", d.Mirrors[0].URL.Host)
+	assert.Equal(t, "unreachable", d.Mirrors[2].URL.Host)
+	assert.Equal(t, 0.0, d.Mirrors[2].Statistics.RankScore)
+}
@@ -0,0 +1,116 @@
+package distributions
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+//go:generate go run ../tools/genbackup -out backup
+
+// Snapshots of the mirror lists of the supported distributions, used when a mirror list can not be fetched.
+// Refresh them with "go generate ./distributions".
+//
+//go:embed backup/*.json
+var backupFiles embed.FS
+
+// Snapshot of the mirror list of a distribution (same format as the JSON source, with some metadata).
+// Snapshots are either generated from the mirror list by tools/genbackup, or curated by hand
+// (without a generation time, and with a description of where the mirrors come from).
+type Backup struct {
+	Distribution string `json:"distribution"`
+	// Release and architecture of the mirror URLs (empty if the mirror URLs do not depend on them)
+	Release      string            `json:"release,omitempty"`
+	Architecture string            `json:"architecture,omitempty"`
+	Generated    time.Time         `json:"generated"`
+	Mirrors      []*mirrors.Mirror `json:"urls"`
+}
+
+// Metadata of an embedded snapshot
+type backupInfo struct {
+	Release      string     `json:"release"`
+	Architecture string     `json:"architecture"`
+	Generated    *time.Time `json:"generated"`
+	Curated      bool       `json:"curated"`
+	Description  string     `json:"description"`
+}
+
+// Name of the snapshot file of the distribution
+func BackupFilename(distro Distributor) string {
+	return strings.ToLower(distro.Name()) + ".json"
+}
+
+// Creates a snapshot of the mirror list of the distribution
+func NewBackup(distro Distributor, mirrorsList []mirrors.Mirror, generated time.Time) Backup {
+	backup := Backup{Distribution: distro.Name(), Generated: generated.UTC()}
+	if releaseSpecific(distro) {
+		backup.Release = distro.(Releaser).Release()
+		if multiArch, ok := distro.(MultiArch); ok {
+			backup.Architecture = multiArch.Architecture()
+		}
+	}
+	backup.Mirrors = make([]*mirrors.Mirror, len(mirrorsList))
+	for i := range mirrorsList {
+		mirror := mirrorsList[i]
+		mirror.Statistics = nil
+		backup.Mirrors[i] = &mirror
+	}
+	return backup
+}
+
+// Whether the mirror URLs of the distribution point to a release (and architecture) specific repository
+func releaseSpecific(distro Distributor) bool {
+	switch distro.(type) {
+	case Fedora, OpenSUSE, YumDistributor:
+		return true
+	default:
+		return false
+	}
+}
+
+// Writes the snapshot as indented JSON
+func (b Backup) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// Returns the embedded snapshot of the mirror list of the distribution and the time it was generated (zero for curated snapshots).
+// Snapshots of release or architecture specific mirror lists are only returned for the same release and architecture.
+func BackupMirrors(distro Distributor) ([]mirrors.Mirror, time.Time, error) {
+	filename := BackupFilename(distro)
+	data, err := backupFiles.ReadFile("backup/" + filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("no backup mirror list for %v", distro.Name())
+	}
+	var backup backupInfo
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, time.Time{}, &mirrors.ParseError{Source: filename, Err: err}
+	}
+	if backup.Generated == nil && !backup.Curated {
+		return nil, time.Time{}, &mirrors.ParseError{Source: filename, Err: errors.New("snapshot is neither generated nor curated")}
+	}
+	if releaseSpecific(distro) {
+		release := distro.(Releaser).Release()
+		if release != backup.Release {
+			return nil, time.Time{}, fmt.Errorf("no backup mirror list for %v %v (only for %v)", distro.Name(), release, backup.Release)
+		}
+		if multiArch, ok := distro.(MultiArch); ok && multiArch.Architecture() != backup.Architecture {
+			return nil, time.Time{}, fmt.Errorf("no backup mirror list for %v %v (only for %v)", distro.Name(), multiArch.Architecture(), backup.Architecture)
+		}
+	}
+	mirrorsList, err := mirrors.ParseMirrorsJSON(data, filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if backup.Curated {
+		return mirrorsList, time.Time{}, nil
+	}
+	return mirrorsList, *backup.Generated, nil
+}
@@ -0,0 +1,85 @@
+package distributions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+// Distribution with an unreachable mirror list
+type unreachableDistributor struct {
+	Debian
+}
+
+func (d unreachableDistributor) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return nil, &mirrors.FetchError{Source: "test", Err: errors.New("unreachable")}
+}
+
+func TestBackupMirrors(t *testing.T) {
+	for _, name := range SupportedDistributions {
+		distro, err := ToDistribution(name)
+		assert.NoError(t, err)
+		mirrorsList, _, err := BackupMirrors(distro)
+		assert.NoError(t, err, name)
+		assert.NotEmpty(t, mirrorsList, name)
+	}
+
+	// Curated snapshots have no generation time
+	_, generated, err := BackupMirrors(Rocky{})
+	assert.NoError(t, err)
+	assert.True(t, generated.IsZero())
+
+	// Release specific snapshots
+	_, _, err = BackupMirrors(Fedora{Version: "rawhide"})
+	assert.Error(t, err)
+	_, _, err = BackupMirrors(Rocky{Arch: "aarch64"})
+	assert.Error(t, err)
+	_, _, err = BackupMirrors(Debian{Suite: "sid"})
+	assert.NoError(t, err)
+	_, _, err = BackupMirrors(CustomDistributor{CustomName: "Test"})
+	assert.Error(t, err)
+}
+
+func TestUpdateMirrorsBackup(t *testing.T) {
+	d := &DistributionMirrors{Distribution: unreachableDistributor{}}
+	assert.NoError(t, d.UpdateMirrorsContext(context.Background(), mirrors.SourceHTTP, ""))
+	assert.NotEmpty(t, d.Mirrors)
+	assert.True(t, d.Stale)
+	var b strings.Builder
+	assert.NoError(t, d.WriteJSON(&b))
+	assert.Contains(t, b.String(), `"stale":true`)
+
+	// No fallback when interrupted or for files
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = &DistributionMirrors{Distribution: CustomDistributor{CustomName: "Test", Definition: &Definition{URL: "http://127.0.0.1:0/", Parser: ParserDefinition{Type: PARSER_TXT}}}}
+	assert.Error(t, d.UpdateMirrorsContext(ctx, mirrors.SourceHTTP, ""))
+	assert.Error(t, d.UpdateMirrorsContext(context.Background(), mirrors.SourceJSON, "missing.json"))
+	assert.False(t, d.Stale)
+
+	// Offline
+	d = &DistributionMirrors{Distribution: Arch{}}
+	assert.NoError(t, d.UpdateMirrorsContext(context.Background(), mirrors.SourceBackup, ""))
+	assert.True(t, d.Stale)
+	assert.Equal(t, "Arch", d.Distribution.Name())
+	assert.NotNil(t, d.Mirrors[0].URL)
+}
+
+func TestNewBackup(t *testing.T) {
+	generated := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	mirrorsList := []mirrors.Mirror{{Statistics: &mirrors.MirrorStatistics{}}}
+	backup := NewBackup(AlmaLinux{}.WithRelease("9"), mirrorsList, generated)
+	assert.Equal(t, "AlmaLinux", backup.Distribution)
+	assert.Equal(t, "9", backup.Release)
+	assert.Equal(t, "x86_64", backup.Architecture)
+	assert.Nil(t, backup.Mirrors[0].Statistics)
+	assert.NotNil(t, mirrorsList[0].Statistics)
+
+	backup = NewBackup(Ubuntu{}, mirrorsList, generated)
+	assert.Empty(t, backup.Release)
+}
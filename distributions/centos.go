@@ -0,0 +1,84 @@
+package distributions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+const (
+	CENTOS_MIRRORS_URL     = "https://mirrors.centos.org/mirrorlist?repo=centos-baseos-%v-stream&arch=%v&protocol=https,http"
+	CENTOS_DEFAULT_RELEASE = "10"
+	CENTOS_DEFAULT_ARCH    = "x86_64"
+)
+
+type CentOSStream struct {
+	// Release version (e.g. "9", "10") of the repositories
+	Version string
+	// Base architecture (e.g. "x86_64", "aarch64") of the repositories
+	Arch string
+}
+
+func (centos CentOSStream) Name() string { return "CentOSStream" }
+
+func (centos CentOSStream) WithRelease(release string) Distributor {
+	centos.Version = release
+	return centos
+}
+
+func (centos CentOSStream) Release() string {
+	if len(centos.Version) == 0 {
+		return CENTOS_DEFAULT_RELEASE
+	}
+	return centos.Version
+}
+
+func (centos CentOSStream) WithArchitecture(arch string) Distributor {
+	centos.Arch = arch
+	return centos
+}
+
+func (centos CentOSStream) Architecture() string {
+	if len(centos.Arch) == 0 {
+		return CENTOS_DEFAULT_ARCH
+	}
+	return centos.Arch
+}
+
+func (centos CentOSStream) Repositories() []string { return []string{"BaseOS", "AppStream"} }
+
+// CentOS Stream 10 and later are signed with a SHA-256 key
+func (centos CentOSStream) GPGKey() string {
+	if major, err := strconv.Atoi(majorVersion(centos.Release())); err == nil && major >= 10 {
+		return "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-centosofficial-SHA256"
+	}
+	return "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-centosofficial"
+}
+
+func (centos CentOSStream) RepoFile() string { return "centos" }
+
+func (centos CentOSStream) Canary() mirrors.Canary { return yumCanary(centos) }
+
+// URL of the mirror list of the BaseOS repository
+func (centos CentOSStream) MirrorlistURL() string {
+	return fmt.Sprintf(CENTOS_MIRRORS_URL, majorVersion(centos.Release()), centos.Architecture())
+}
+
+func (centos CentOSStream) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return centos.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (centos CentOSStream) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		return fetchYumMirrorlistContext(ctx, centos.MirrorlistURL(), "BaseOS", centos.Architecture())
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
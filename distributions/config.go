@@ -0,0 +1,84 @@
+package distributions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Package manager configuration file of a distribution
+type ConfigFile struct {
+	// Path of the file (including the root directory)
+	Path string
+	// Output format of the file content
+	Format string
+}
+
+// Finds the package manager configuration file of the distribution, under the root directory (e.g. "/" or a chroot).
+// For APT distributions the deb822 sources of the distribution are preferred if they exist (default since Debian 13 and Ubuntu 24.04),
+// otherwise the classic sources.list is used.
+// For dnf/yum distributions this is the default repository file, see FindConfigFiles for the files that are in use.
+func FindConfigFile(distro Distributor, root string) (ConfigFile, error) {
+	switch distro.(type) {
+	case Arch:
+		return ConfigFile{Path: filepath.Join(root, "etc/pacman.d/mirrorlist"), Format: "pacman"}, nil
+	case Alpine:
+		return ConfigFile{Path: filepath.Join(root, "etc/apk/repositories"), Format: "apk"}, nil
+	case YumDistributor:
+		return ConfigFile{Path: filepath.Join(root, "etc/yum.repos.d", distro.(YumDistributor).RepoFile()+".repo"), Format: "repo"}, nil
+	case AptDistributor:
+		deb822 := filepath.Join(root, "etc/apt/sources.list.d", strings.ToLower(distro.Name())+".sources")
+		_, err := os.Stat(deb822)
+		if err == nil {
+			return ConfigFile{Path: deb822, Format: "deb822"}, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return ConfigFile{}, err
+		}
+		return ConfigFile{Path: filepath.Join(root, "etc/apt/sources.list"), Format: "sources.list"}, nil
+	default:
+		return ConfigFile{}, fmt.Errorf("system configuration is not supported for %v", distro.Name())
+	}
+}
+
+// Finds the package manager configuration files of the distribution, under the root directory (see FindConfigFile).
+// dnf/yum repositories may be split over multiple files (e.g. almalinux-baseos.repo and almalinux-appstream.repo on AlmaLinux 9),
+// so all files in /etc/yum.repos.d that define any of the repositories (default: repositories of the distribution) are returned,
+// or the default repository file if there are none.
+func FindConfigFiles(distro Distributor, root string, repos []string) ([]ConfigFile, error) {
+	configFile, err := FindConfigFile(distro, root)
+	if err != nil {
+		return nil, err
+	}
+	yumDistro, ok := distro.(YumDistributor)
+	if !ok {
+		return []ConfigFile{configFile}, nil
+	}
+	if len(repos) == 0 {
+		repos = yumDistro.Repositories()
+	}
+	filenames, err := filepath.Glob(filepath.Join(root, "etc/yum.repos.d", "*.repo"))
+	if err != nil {
+		return nil, err
+	}
+	configFiles := []ConfigFile{}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		sections := repoSections(data)
+		for _, repo := range repos {
+			if sections[strings.ToLower(repo)] {
+				configFiles = append(configFiles, ConfigFile{Path: filename, Format: "repo"})
+				break
+			}
+		}
+	}
+	if len(configFiles) == 0 {
+		return []ConfigFile{configFile}, nil
+	}
+	return configFiles, nil
+}
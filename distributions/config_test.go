@@ -0,0 +1,70 @@
+package distributions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindConfigFile(t *testing.T) {
+	root := t.TempDir()
+
+	configFile, err := FindConfigFile(Arch{}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFile{Path: filepath.Join(root, "etc/pacman.d/mirrorlist"), Format: "pacman"}, configFile)
+
+	configFile, err = FindConfigFile(Alpine{}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFile{Path: filepath.Join(root, "etc/apk/repositories"), Format: "apk"}, configFile)
+
+	configFile, err = FindConfigFile(AlmaLinux{}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFile{Path: filepath.Join(root, "etc/yum.repos.d/almalinux.repo"), Format: "repo"}, configFile)
+
+	configFile, err = FindConfigFile(Ubuntu{}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFile{Path: filepath.Join(root, "etc/apt/sources.list"), Format: "sources.list"}, configFile)
+
+	// deb822 sources are preferred if they exist
+	deb822 := filepath.Join(root, "etc/apt/sources.list.d/ubuntu.sources")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(deb822), 0755))
+	assert.NoError(t, os.WriteFile(deb822, []byte{}, 0644))
+	configFile, err = FindConfigFile(Ubuntu{}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFile{Path: deb822, Format: "deb822"}, configFile)
+
+	_, err = FindConfigFile(CustomDistributor{CustomName: "Test"}, root)
+	assert.Error(t, err)
+}
+
+func TestFindConfigFiles(t *testing.T) {
+	root := t.TempDir()
+
+	// Default repository file if no file defines the repositories
+	configFiles, err := FindConfigFiles(AlmaLinux{}, root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigFile{{Path: filepath.Join(root, "etc/yum.repos.d/almalinux.repo"), Format: "repo"}}, configFiles)
+
+	// Repositories split over multiple files
+	repoDir := filepath.Join(root, "etc/yum.repos.d")
+	assert.NoError(t, os.MkdirAll(repoDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "almalinux-baseos.repo"), []byte("[baseos]\n[baseos-source]\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "almalinux-appstream.repo"), []byte("[appstream]\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "almalinux-crb.repo"), []byte("[crb]\n"), 0644))
+	configFiles, err = FindConfigFiles(AlmaLinux{}, root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigFile{
+		{Path: filepath.Join(repoDir, "almalinux-appstream.repo"), Format: "repo"},
+		{Path: filepath.Join(repoDir, "almalinux-baseos.repo"), Format: "repo"},
+	}, configFiles)
+
+	configFiles, err = FindConfigFiles(AlmaLinux{}, root, []string{"CRB"})
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigFile{{Path: filepath.Join(repoDir, "almalinux-crb.repo"), Format: "repo"}}, configFiles)
+
+	configFiles, err = FindConfigFiles(Arch{}, root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigFile{{Path: filepath.Join(root, "etc/pacman.d/mirrorlist"), Format: "pacman"}}, configFiles)
+}
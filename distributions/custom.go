@@ -1,31 +1,53 @@
 package distributions
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
 
 	"github.com/thanoskoutr/gomirror/mirrors"
 )
 
-// TODO: Add generic Distributor implementation for using tool with non natively supported distributions
+// Generic Distributor for using the tool with non natively supported distributions.
+// Without a definition, only JSON and TXT sources are supported.
 type CustomDistributor struct {
 	CustomName string
+	// Definition of the distribution, loaded from a definition file (optional)
+	Definition *Definition
 }
 
 func (d *CustomDistributor) SetName(name string) { d.CustomName = name }
 
-func (d CustomDistributor) Name() string { return d.CustomName }
+func (d CustomDistributor) Name() string {
+	if len(d.CustomName) == 0 && d.Definition != nil {
+		return d.Definition.Name
+	}
+	return d.CustomName
+}
+
+func (d CustomDistributor) ProbePath() string {
+	if d.Definition == nil {
+		return ""
+	}
+	return d.Definition.ProbePath
+}
+
+func (d CustomDistributor) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return d.GetMirrorsContext(context.Background(), source, filename)
+}
 
-func (d CustomDistributor) GetMirrors(source mirrors.MirrorSource, filename string) []mirrors.Mirror {
+func (d CustomDistributor) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
 	switch source {
 	case mirrors.SourceHTTP:
-		// TODO: Not supported for unknown distributions, except if in known and valid JSON or TXT format
-		log.Fatal("Unimplemented functionality: sourceHTTP")
-		return []mirrors.Mirror{}
+		if d.Definition == nil {
+			return nil, errors.New("unsupported source type for custom distributions without a definition: http")
+		}
+		return d.Definition.FetchMirrorsContext(ctx)
 	case mirrors.SourceJSON:
 		return mirrors.ReadMirrorsJSON(filename)
 	case mirrors.SourceTXT:
 		return mirrors.ReadMirrorsTXT(filename)
 	default:
-		return []mirrors.Mirror{}
+		return nil, fmt.Errorf("unsupported source type: %v", source)
 	}
 }
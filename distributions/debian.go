@@ -1,6 +1,12 @@
 package distributions
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"strings"
@@ -11,48 +17,268 @@ import (
 )
 
 const (
-	DEBIAN_MIRRORS_URL = "https://www.debian.org/mirror/list"
+	DEBIAN_MASTERLIST_URL = "https://salsa.debian.org/mirror-team/masterlist/-/raw/master/Mirrors.masterlist"
+	DEBIAN_MIRRORS_URL    = "https://www.debian.org/mirror/list"
+	DEBIAN_UPSTREAM_URL   = "https://deb.debian.org/debian/"
+	DEBIAN_SECURITY_URL   = "http://security.debian.org/debian-security/"
+	DEBIAN_KEYRING        = "/usr/share/keyrings/debian-archive-keyring.gpg"
+	DEBIAN_DEFAULT_SUITE  = "stable"
 )
 
-type Debian struct{}
+type Debian struct {
+	// Suite (codename or alias like "stable") used for release specific operations
+	Suite string
+}
 
 func (deb Debian) Name() string { return "Debian" }
 
-func (deb Debian) GetMirrors(source mirrors.MirrorSource, filename string) []mirrors.Mirror {
+func (deb Debian) WithRelease(release string) Distributor {
+	deb.Suite = release
+	return deb
+}
+
+func (deb Debian) Release() string {
+	if len(deb.Suite) == 0 {
+		return DEBIAN_DEFAULT_SUITE
+	}
+	return deb.Suite
+}
+
+func (deb Debian) UpstreamURL() string { return DEBIAN_UPSTREAM_URL }
+
+func (deb Debian) Components() []string { return []string{"main"} }
+
+func (deb Debian) Keyring() string { return DEBIAN_KEYRING }
+
+// Security updates of releases older than bullseye use the "<suite>/updates" suite,
+// and unstable/testing have no security updates
+func (deb Debian) SecuritySource(suite string) (string, string) {
+	switch suite {
+	case "sid", "unstable", "experimental", "rc-buggy":
+		return "", ""
+	case "jessie", "stretch", "buster":
+		return DEBIAN_SECURITY_URL, suite + "/updates"
+	default:
+		return DEBIAN_SECURITY_URL, suite + "-security"
+	}
+}
+
+// Compressed listing of the whole archive (several MB)
+func (deb Debian) SpeedTestPath() string { return "ls-lR.gz" }
+
+// Signed Release file of the suite (its date is the freshness of the mirror)
+func (deb Debian) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: InReleasePath(deb.Release()), Signature: mirrors.SIGNATURE_PGP_SIGNED, ReleaseDate: true}
+}
+
+func (deb Debian) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return deb.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (deb Debian) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
 	switch source {
 	case mirrors.SourceHTTP:
-		// TODO: If error online fallback to internal mirrors
-		return FetchDebianMirrors(DEBIAN_MIRRORS_URL)
+		mirrorsList, err := FetchDebianMasterlistContext(ctx, DEBIAN_MASTERLIST_URL)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error: Failed to fetch masterlist, falling back to HTML mirror list:", err)
+			return FetchDebianMirrorsContext(ctx, DEBIAN_MIRRORS_URL)
+		}
+		return mirrorsList, err
 	case mirrors.SourceJSON:
 		return mirrors.ReadMirrorsJSON(filename)
 	case mirrors.SourceTXT:
 		return mirrors.ReadMirrorsTXT(filename)
 	default:
-		return []mirrors.Mirror{}
+		return nil, fmt.Errorf("unsupported source type: %v", source)
 	}
 }
 
-// TODO: Error handling
-func FetchDebianMirrors(URL string) []mirrors.Mirror {
+func FetchDebianMasterlist(URL string) ([]mirrors.Mirror, error) {
+	return FetchDebianMasterlistContext(context.Background(), URL)
+}
+
+// Same as FetchDebianMasterlist, but the request is aborted when the context is done.
+func FetchDebianMasterlistContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	mirrorsList, err := ParseDebianMasterlist(bytes.NewReader(resp))
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Parses the mirrors of Mirrors.masterlist (RFC822 stanzas, one for each site).
+// Each site has one mirror for each protocol of its archive (Archive-http, Archive-https, Archive-ftp, Archive-rsync).
+// Sites listed in Includes serve the archive of the including site (e.g. behind a ftp.<country>.debian.org alias):
+// a site without archive fields takes them from the sites it includes, and included sites without a stanza
+// of their own are mirrors with the archive of the including site.
+func ParseDebianMasterlist(r io.Reader) ([]mirrors.Mirror, error) {
+	stanzas, err := parseStanzas(r)
+	if err != nil {
+		return nil, err
+	}
+	sites := map[string]map[string]string{}
+	for _, stanza := range stanzas {
+		if site := stanza["Site"]; len(site) != 0 {
+			sites[site] = stanza
+		}
+	}
+	mirrorsList := []mirrors.Mirror{}
+	seen := map[string]bool{}
+	add := func(site string, stanza map[string]string) {
+		for _, mirror := range debianArchiveMirrors(site, stanza) {
+			if !seen[mirror.URL.String()] {
+				seen[mirror.URL.String()] = true
+				mirrorsList = append(mirrorsList, mirror)
+			}
+		}
+	}
+	for _, stanza := range stanzas {
+		site := stanza["Site"]
+		if len(site) == 0 {
+			continue
+		}
+		includes := strings.Fields(stanza["Includes"])
+		archive := stanza
+		if !hasDebianArchive(stanza) {
+			// Take the archive of the first included site that has one
+			for _, include := range includes {
+				if included, ok := sites[include]; ok && hasDebianArchive(included) {
+					archive = map[string]string{}
+					for field, value := range included {
+						archive[field] = value
+					}
+					archive["Country"] = stanza["Country"]
+					break
+				}
+			}
+		}
+		add(site, archive)
+		for _, include := range includes {
+			if _, ok := sites[include]; !ok {
+				add(include, archive)
+			}
+		}
+	}
+	return mirrorsList, nil
+}
+
+// Reports whether a masterlist site has a package archive
+func hasDebianArchive(stanza map[string]string) bool {
+	for _, protocol := range []string{"http", "https", "ftp", "rsync"} {
+		if _, ok := stanza["Archive-"+protocol]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the mirrors of the archive of a masterlist stanza, served by the given site
+func debianArchiveMirrors(site string, stanza map[string]string) []mirrors.Mirror {
+	// Country is a code followed by a name (e.g. "GR Greece")
+	countryCode, country := "", ""
+	if fields := strings.SplitN(stanza["Country"], " ", 2); len(fields) == 2 {
+		countryCode, country = strings.ToUpper(fields[0]), strings.TrimSpace(fields[1])
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, protocol := range []string{"http", "https", "ftp", "rsync"} {
+		path, ok := stanza["Archive-"+protocol]
+		if !ok {
+			continue
+		}
+		proto, _ := mirrors.ToProtocol(protocol)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		mirrorsList = append(mirrorsList, mirrors.Mirror{
+			Country:       country,
+			CountryCode:   countryCode,
+			URL:           &url.URL{Scheme: protocol, Host: site, Path: path},
+			Protocol:      proto,
+			Architectures: stanza["Archive-architecture"],
+			IPv6:          strings.EqualFold(stanza["IPv6"], "yes"),
+		})
+	}
+	return mirrorsList
+}
+
+// Parses RFC822 style stanzas (fields separated by blank lines, with continuation lines starting with whitespace)
+func parseStanzas(r io.Reader) ([]map[string]string, error) {
+	stanzas := []map[string]string{}
+	stanza := map[string]string{}
+	field := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
+			if len(stanza) != 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = map[string]string{}
+			}
+			field = ""
+		case strings.HasPrefix(line, "#"):
+		case line[0] == ' ' || line[0] == '\t':
+			if len(field) == 0 {
+				return nil, fmt.Errorf("continuation line without field: %q", line)
+			}
+			stanza[field] += " " + strings.TrimSpace(line)
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid field: %q", line)
+			}
+			field = strings.TrimSpace(name)
+			stanza[field] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(stanza) != 0 {
+		stanzas = append(stanzas, stanza)
+	}
+	return stanzas, nil
+}
+
+func FetchDebianMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchDebianMirrorsContext(context.Background(), URL)
+}
+
+// Same as FetchDebianMirrors, but the request is aborted when the context is done.
+func FetchDebianMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
 	// Definitions
 	countries := []string{}
 	countryCnt := 0
 	mirrorsList := []mirrors.Mirror{}
 
 	// Make request
-	resp, err := soup.Get(URL)
+	resp, err := utils.GetRequestContext(ctx, URL)
 	if err != nil {
-		log.Fatal("Can not parse Debian mirrors list: ", err)
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
 	}
 	// Parse HTML mirror list
-	doc := soup.HTMLParse(resp)
+	doc := soup.HTMLParse(string(resp))
 	content := doc.Find("div", "id", "content")
+	if content.Pointer == nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing content section")}
+	}
 	tables := content.FindAll("table")
 	if len(tables) < 3 {
-		log.Fatal("Can not parse Debian mirrors list")
+		return nil, &mirrors.ParseError{Source: URL, Err: fmt.Errorf("expected at least 3 tables, found %v", len(tables))}
 	}
 	// Parse table rows
-	trs := tables[1].Find("tbody").FindAll("tr")
+	tbody := tables[1].Find("tbody")
+	if tbody.Pointer == nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing mirrors table body")}
+	}
+	trs := tbody.FindAll("tr")
 	for _, tr := range trs {
 		tds := tr.FindAll("td")
 		// Find Architectures
@@ -72,6 +298,9 @@ func FetchDebianMirrors(URL string) []mirrors.Mirror {
 			// Find mirror link
 			a := td.Find("a")
 			if a.Pointer != nil {
+				if countryCnt == 0 {
+					return nil, &mirrors.ParseError{Source: URL, Err: errors.New("mirror listed before any country")}
+				}
 				link := a.Attrs()["href"]
 				// Parse URL
 				urlStr, err := url.Parse(link)
@@ -89,5 +318,8 @@ func FetchDebianMirrors(URL string) []mirrors.Mirror {
 			}
 		}
 	}
-	return mirrorsList
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
 }
@@ -1,3 +0,0 @@
-package distributions
-
-var DebianBackupMirrors map[string]interface{}
@@ -0,0 +1,72 @@
+package distributions
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchDebianMasterlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/Mirrors.masterlist")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchDebianMasterlist(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 8)
+
+	first := mirrorsList[0]
+	assert.Equal(t, "http://ftp.gr.debian.org/debian/", first.URL.String())
+	assert.Equal(t, mirrors.ProtoHTTP, first.Protocol)
+	assert.Equal(t, "GR", first.CountryCode)
+	assert.Equal(t, "Greece", first.Country)
+	assert.Equal(t, "amd64 arm64 armel armhf i386 mips64el ppc64el s390x", first.Architectures)
+	assert.True(t, first.IPv6)
+
+	assert.Equal(t, "rsync://ftp.gr.debian.org/debian/", mirrorsList[1].URL.String())
+	assert.Equal(t, mirrors.ProtoRSYNC, mirrorsList[1].Protocol)
+	assert.Equal(t, "ftp://debian.otenet.gr/pub/linux/debian/", mirrorsList[5].URL.String())
+	assert.False(t, mirrorsList[5].IPv6)
+	assert.Equal(t, "https://mirrors.dotsrc.org/debian/", mirrorsList[6].URL.String())
+	assert.Equal(t, "DK", mirrorsList[6].CountryCode)
+}
+
+func TestParseDebianMasterlistIncludes(t *testing.T) {
+	data, err := os.ReadFile("testdata/Mirrors.masterlist")
+	assert.NoError(t, err)
+	mirrorsList, err := ParseDebianMasterlist(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	// Included site without a stanza serves the archive of the including site
+	assert.Equal(t, "http://ftp.cc.uoc.gr/debian/", mirrorsList[2].URL.String())
+	assert.Equal(t, "rsync://ftp.cc.uoc.gr/debian/", mirrorsList[3].URL.String())
+	assert.Equal(t, "Greece", mirrorsList[2].Country)
+	assert.True(t, mirrorsList[2].IPv6)
+
+	// Site without an archive takes it from the included site
+	alias := mirrorsList[7]
+	assert.Equal(t, "https://ftp.dk.debian.org/debian/", alias.URL.String())
+	assert.Equal(t, "DK", alias.CountryCode)
+	assert.Equal(t, "any", alias.Architectures)
+}
+
+func TestParseDebianMasterlistInvalid(t *testing.T) {
+	_, err := ParseDebianMasterlist(strings.NewReader(" continuation\n"))
+	assert.Error(t, err)
+	_, err = ParseDebianMasterlist(strings.NewReader("Site ftp.gr.debian.org\n"))
+	assert.Error(t, err)
+}
+
+func TestFetchDebianMasterlistError(t *testing.T) {
+	_, err := FetchDebianMasterlistContext(context.Background(), "http://127.0.0.1:0/Mirrors.masterlist")
+	var fetchErr *mirrors.FetchError
+	assert.ErrorAs(t, err, &fetchErr)
+}
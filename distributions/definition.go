@@ -0,0 +1,292 @@
+package distributions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported parsers of definition files
+const (
+	PARSER_TXT      = "txt"
+	PARSER_JSON     = "json"
+	PARSER_METALINK = "metalink"
+	PARSER_HTML     = "html"
+)
+
+// Declarative definition of a distribution, loaded from a YAML or JSON file
+type Definition struct {
+	// Name of the distribution
+	Name string `yaml:"name" json:"name"`
+	// URL of the mirror list
+	URL string `yaml:"url" json:"url"`
+	// Parser of the mirror list
+	Parser ParserDefinition `yaml:"parser" json:"parser"`
+	// File requested to measure response time, relative to the mirror URL (optional)
+	ProbePath string `yaml:"probe_path" json:"probe_path"`
+	// Go template (text/template) of the "template" output (optional)
+	Output string `yaml:"output" json:"output"`
+
+	template *template.Template
+}
+
+// Parser of the mirror list of a definition
+type ParserDefinition struct {
+	// One of: "txt" (one URL per line), "json", "metalink", "html"
+	Type string `yaml:"type" json:"type"`
+	// JSONPath of the mirror entries (json, default "$[*]"),
+	// or CSS selector of the mirror entries, e.g. table rows (html)
+	Items string `yaml:"items" json:"items"`
+	// JSONPath of the URL, relative to each entry (json),
+	// or CSS selector of the link, relative to each entry (html, default "a")
+	URL string `yaml:"url" json:"url"`
+	// JSONPath of the country name or code, relative to each entry (json),
+	// or CSS selector of the country, relative to each entry (html) (optional)
+	Country string `yaml:"country" json:"country"`
+	// Path of the listed file on the mirrors, removed from the URLs (optional, e.g. "repodata/repomd.xml")
+	Suffix string `yaml:"suffix" json:"suffix"`
+}
+
+// Data of the output template
+type templateData struct {
+	Name    string
+	Mirrors []*mirrors.Mirror
+}
+
+// Loads a definition from a YAML or JSON file (based on its extension, YAML by default)
+func LoadDefinition(filename string) (*Definition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	def := &Definition{}
+	if strings.HasSuffix(strings.ToLower(filename), ".json") {
+		err = json.Unmarshal(data, def)
+	} else {
+		err = yaml.Unmarshal(data, def)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid definition %v: %w", filename, err)
+	}
+	if err := def.validate(); err != nil {
+		return nil, fmt.Errorf("invalid definition %v: %w", filename, err)
+	}
+	return def, nil
+}
+
+// Checks the required fields and fills in the defaults
+func (def *Definition) validate() error {
+	if len(def.Name) == 0 {
+		return errors.New("missing name")
+	}
+	if _, err := url.ParseRequestURI(def.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	switch def.Parser.Type {
+	case PARSER_TXT, PARSER_METALINK:
+	case PARSER_JSON:
+		if len(def.Parser.Items) == 0 {
+			def.Parser.Items = "$[*]"
+		}
+		if len(def.Parser.URL) == 0 {
+			return errors.New("missing JSONPath of the mirror URL")
+		}
+		for _, path := range []string{def.Parser.Items, def.Parser.URL, def.Parser.Country} {
+			if len(path) != 0 {
+				if _, err := evalJSONPath(path, nil); err != nil {
+					return err
+				}
+			}
+		}
+	case PARSER_HTML:
+		if len(def.Parser.Items) == 0 {
+			return errors.New("missing CSS selector of the mirror entries")
+		}
+		if len(def.Parser.URL) == 0 {
+			def.Parser.URL = "a"
+		}
+		for _, selector := range []string{def.Parser.Items, def.Parser.URL, def.Parser.Country} {
+			if len(selector) != 0 {
+				if _, err := parseSelector(selector); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported parser: %q", def.Parser.Type)
+	}
+	if len(def.Output) != 0 {
+		tmpl, err := template.New(def.Name).Parse(def.Output)
+		if err != nil {
+			return fmt.Errorf("invalid output template: %w", err)
+		}
+		def.template = tmpl
+	}
+	return nil
+}
+
+// Fetches and parses the mirror list of the definition
+func (def *Definition) FetchMirrorsContext(ctx context.Context) ([]mirrors.Mirror, error) {
+	// Make request
+	resp, err := utils.GetRequestContext(ctx, def.URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: def.URL, Err: err}
+	}
+	mirrorsList, err := def.ParseMirrors(resp)
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: def.URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Parses a mirror list with the parser of the definition
+func (def *Definition) ParseMirrors(data []byte) ([]mirrors.Mirror, error) {
+	switch def.Parser.Type {
+	case PARSER_TXT:
+		return ParsePlainMirrorlist(data, def.Parser.Suffix, "")
+	case PARSER_METALINK:
+		return ParseMetalink(data, def.Parser.Suffix)
+	case PARSER_JSON:
+		return def.parseJSON(data)
+	case PARSER_HTML:
+		return def.parseHTML(data)
+	default:
+		return nil, fmt.Errorf("unsupported parser: %q", def.Parser.Type)
+	}
+}
+
+func (def *Definition) parseJSON(data []byte) ([]mirrors.Mirror, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	items, err := evalJSONPath(def.Parser.Items, doc)
+	if err != nil {
+		return nil, err
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, item := range items {
+		link := firstJSONString(def.Parser.URL, item)
+		country := ""
+		if len(def.Parser.Country) != 0 {
+			country = firstJSONString(def.Parser.Country, item)
+		}
+		mirror, err := definitionMirror(link, country, def.Parser.Suffix)
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	return mirrorsList, nil
+}
+
+func (def *Definition) parseHTML(data []byte) ([]mirrors.Mirror, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	// Selectors are validated when the definition is loaded
+	itemsSelector, _ := parseSelector(def.Parser.Items)
+	urlSelector, _ := parseSelector(def.Parser.URL)
+	var countrySelector []simpleSelector
+	if len(def.Parser.Country) != 0 {
+		countrySelector, _ = parseSelector(def.Parser.Country)
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, item := range selectAll(doc, itemsSelector) {
+		links := selectAll(item, urlSelector)
+		if len(links) == 0 {
+			continue
+		}
+		// Links are taken from the href attribute, other elements from their text
+		link := nodeAttr(links[0], "href")
+		if len(link) == 0 {
+			link = nodeText(links[0])
+		}
+		country := ""
+		if countries := selectAll(item, countrySelector); len(countries) != 0 {
+			country = nodeText(countries[0])
+		}
+		mirror, err := definitionMirror(link, country, def.Parser.Suffix)
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	return mirrorsList, nil
+}
+
+// Returns the first string matching the JSONPath (empty if none)
+func firstJSONString(path string, value interface{}) string {
+	values, err := evalJSONPath(path, value)
+	if err != nil {
+		return ""
+	}
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// Creates a mirror from its URL and its country (name or 2 letter code)
+func definitionMirror(link string, country string, suffix string) (mirrors.Mirror, error) {
+	if len(link) == 0 {
+		return mirrors.Mirror{}, errors.New("empty URL")
+	}
+	urlStr, err := url.Parse(strings.TrimSuffix(link, suffix))
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	proto, err := mirrors.ToProtocol(urlStr.Scheme)
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	mirror := mirrors.Mirror{URL: urlStr, Protocol: proto}
+	if len(country) == 2 {
+		mirror.CountryCode = strings.ToUpper(country)
+		mirror.Country = utils.GetCountryName(mirror.CountryCode)
+	} else if len(country) != 0 {
+		mirror.Country = country
+		mirror.CountryCode = utils.GetCountryCode(country)
+	}
+	return mirror, nil
+}
+
+// Options for template output
+type TemplateOptions struct {
+	// Number of mirrors passed to the template, in ranked order (default: all reachable mirrors)
+	Top int
+}
+
+// Writes the mirrors in ranked order with the output template of the definition
+func (d *DistributionMirrors) WriteTemplate(w io.Writer, opts TemplateOptions) error {
+	distro, ok := d.Distribution.(CustomDistributor)
+	if !ok || distro.Definition == nil || distro.Definition.template == nil {
+		return fmt.Errorf("template output is not supported for %v", d.Distribution.Name())
+	}
+	best := d.usableMirrors(opts.Top, "http", "https", "ftp", "rsync")
+	if len(best) == 0 {
+		return errors.New("no reachable mirror")
+	}
+	return distro.Definition.template.Execute(w, templateData{Name: distro.Name(), Mirrors: best})
+}
@@ -0,0 +1,147 @@
+package distributions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestDefinitionParseJSON(t *testing.T) {
+	def := &Definition{Name: "Arch", URL: "https://archlinux.org/mirrors/status/json/", Parser: ParserDefinition{
+		Type:    PARSER_JSON,
+		Items:   "$.urls[*]",
+		URL:     "$.url",
+		Country: "$['country_code']",
+	}}
+	assert.NoError(t, def.validate())
+	data, err := os.ReadFile("../inputs/arch.json")
+	assert.NoError(t, err)
+
+	mirrorsList, err := def.ParseMirrors(data)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, mirrorsList)
+	assert.Equal(t, "https://mirror.aarnet.edu.au/pub/archlinux/", mirrorsList[0].URL.String())
+	assert.Equal(t, mirrors.ProtoHTTPS, mirrorsList[0].Protocol)
+	assert.Equal(t, "AU", mirrorsList[0].CountryCode)
+	assert.Equal(t, "Australia", mirrorsList[0].Country)
+}
+
+func TestDefinitionParseHTML(t *testing.T) {
+	def := &Definition{Name: "Test", URL: "https://example.com/mirrors.html", Parser: ParserDefinition{
+		Type:    PARSER_HTML,
+		Items:   "table.mirrors tr",
+		Country: "td.country",
+	}}
+	assert.NoError(t, def.validate())
+	data, err := os.ReadFile("testdata/definition.html")
+	assert.NoError(t, err)
+
+	mirrorsList, err := def.ParseMirrors(data)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 2)
+	assert.Equal(t, "https://ftp.fau.de/archlinux/", mirrorsList[0].URL.String())
+	assert.Equal(t, "DE", mirrorsList[0].CountryCode)
+	assert.Equal(t, "Germany", mirrorsList[0].Country)
+	assert.Equal(t, mirrors.ProtoHTTP, mirrorsList[1].Protocol)
+	assert.Equal(t, "Greece", mirrorsList[1].Country)
+}
+
+func TestDefinitionParseTXTFileSuffix(t *testing.T) {
+	def := &Definition{Name: "Test", URL: "https://example.com/mirrors.txt", Parser: ParserDefinition{
+		Type:   PARSER_TXT,
+		Suffix: "repodata/repomd.xml",
+	}}
+	assert.NoError(t, def.validate())
+
+	mirrorsList, err := def.ParseMirrors([]byte("# Mirrors\nhttps://ftp.fau.de/test/repodata/repomd.xml\nhttp://ftp.cc.uoc.gr/test/repodata/repomd.xml\n"))
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 2)
+	assert.Equal(t, "https://ftp.fau.de/test/", mirrorsList[0].URL.String())
+	assert.Equal(t, "http://ftp.cc.uoc.gr/test/", mirrorsList[1].URL.String())
+
+	// None of the URLs matches the suffix
+	_, err = def.ParseMirrors([]byte("https://ftp.fau.de/test/\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadDefinition(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# Mirrors\nhttps://ftp.fau.de/test/\nhttp://ftp.cc.uoc.gr/test\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.yaml")
+	content := `name: Test
+url: ` + server.URL + `
+parser:
+  type: txt
+probe_path: index.txt
+output: |
+  {{- range .Mirrors }}
+  mirror={{ .URL }}
+  {{- end }}
+`
+	assert.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+	def, err := LoadDefinition(filename)
+	assert.NoError(t, err)
+	distro := CustomDistributor{Definition: def}
+	assert.Equal(t, "Test", distro.Name())
+	assert.Equal(t, "index.txt", distro.ProbePath())
+
+	d := &DistributionMirrors{Distribution: distro}
+	assert.NoError(t, d.UpdateMirrors(mirrors.SourceHTTP, ""))
+	assert.Len(t, d.Mirrors, 2)
+	for _, mirror := range d.Mirrors {
+		mirror.Statistics = &mirrors.MirrorStatistics{AvgResponseTimeHTTP: time.Millisecond}
+	}
+	var b strings.Builder
+	assert.NoError(t, d.WriteTemplate(&b, TemplateOptions{}))
+	assert.Equal(t, "\nmirror=https://ftp.fau.de/test/\nmirror=http://ftp.cc.uoc.gr/test/\n", b.String())
+
+	// JSON definitions
+	filename = filepath.Join(dir, "test.json")
+	assert.NoError(t, os.WriteFile(filename, []byte(`{"name": "Test", "url": "`+server.URL+`", "parser": {"type": "metalink"}}`), 0644))
+	_, err = LoadDefinition(filename)
+	assert.NoError(t, err)
+
+	// Invalid definitions
+	for _, content := range []string{
+		"url: https://example.com/\nparser:\n  type: txt\n",
+		"name: Test\nurl: https://example.com/\nparser:\n  type: csv\n",
+		"name: Test\nurl: https://example.com/\nparser:\n  type: json\n  url: urls[0]\n",
+		"name: Test\nurl: https://example.com/\nparser:\n  type: html\n",
+		"name: Test\nurl: https://example.com/\nparser:\n  type: txt\noutput: '{{ .Mirrors'\n",
+	} {
+		filename = filepath.Join(dir, "invalid.yaml")
+		assert.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+		_, err = LoadDefinition(filename)
+		assert.Error(t, err, content)
+	}
+
+	// Template output requires a definition with a template
+	d.Distribution = CustomDistributor{CustomName: "Test"}
+	assert.Error(t, d.WriteTemplate(&b, TemplateOptions{}))
+}
+
+func TestDefinitionProbePath(t *testing.T) {
+	requests := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/test/")
+	d := &DistributionMirrors{Distribution: CustomDistributor{CustomName: "Test"}, Mirrors: []*mirrors.Mirror{{URL: u}}}
+	d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 1, Canary: mirrors.Canary{Path: "index.txt"}})
+	assert.Equal(t, "/test/index.txt", <-requests)
+}
@@ -1,6 +1,7 @@
 package distributions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/thanoskoutr/gomirror/mirrors"
@@ -9,9 +10,58 @@ import (
 type Distributor interface {
 	Name() string
 	// TODO: Return []*mirrors.Mirror
-	GetMirrors(source mirrors.MirrorSource, filename string) []mirrors.Mirror
+	// Returns a *mirrors.FetchError, *mirrors.ParseError or mirrors.ErrNoMirrors on failure
+	GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error)
 }
 
+// Implemented by distributions that can abort fetching their mirror list when the context is done
+type ContextDistributor interface {
+	Distributor
+	GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error)
+}
+
+// Implemented by distributions that have a known large file on all their mirrors, used to measure download speed
+type SpeedTester interface {
+	// Path of the file, relative to the mirror URL
+	SpeedTestPath() string
+}
+
+// Implemented by distributions that have a known small file on all their mirrors, requested to measure response time
+type Prober interface {
+	// Path of the file, relative to the mirror URL (empty means the mirror URL)
+	ProbePath() string
+}
+
+// Implemented by distributions that have a known file on all their mirrors, used to validate
+// that mirrors serve the repository (see mirrors.Canary)
+type Validator interface {
+	Canary() mirrors.Canary
+}
+
+// Implemented by distributions that exclude some mirrors of their list (e.g. outdated), whatever the source of the list
+type MirrorFilter interface {
+	KeepMirror(mirror mirrors.Mirror) bool
+}
+
+// Implemented by distributions that publish multiple releases (e.g. suites, versions) on their mirrors
+type Releaser interface {
+	Distributor
+	// Returns a copy of the distribution for the given release (empty means the default release)
+	WithRelease(release string) Distributor
+	Release() string
+}
+
+// Implemented by distributions that publish a mirror list for each architecture
+type MultiArch interface {
+	Distributor
+	// Returns a copy of the distribution for the given architecture (empty means the default architecture)
+	WithArchitecture(arch string) Distributor
+	Architecture() string
+}
+
+// Names of the natively supported distributions
+var SupportedDistributions = []string{"AlmaLinux", "Alpine", "Arch", "CentOSStream", "Debian", "Fedora", "Gentoo", "OpenSUSE", "Rocky", "Ubuntu"}
+
 func ToDistribution(distro string) (Distributor, error) {
 	switch distro {
 	case "Ubuntu":
@@ -20,6 +70,22 @@ func ToDistribution(distro string) (Distributor, error) {
 		return Debian{}, nil
 	case "Arch":
 		return Arch{}, nil
+	case "Alpine":
+		return Alpine{}, nil
+	case "Fedora":
+		return Fedora{}, nil
+	case "Gentoo":
+		return Gentoo{}, nil
+	case "OpenSUSE":
+		return OpenSUSE{}, nil
+	case "Rocky":
+		return Rocky{}, nil
+	case "AlmaLinux":
+		return AlmaLinux{}, nil
+	case "CentOSStream":
+		return CentOSStream{}, nil
+	case "Custom":
+		return CustomDistributor{CustomName: distro}, nil
 	default:
 		return nil, fmt.Errorf("unsupported distribution: %v", distro)
 	}
@@ -1,11 +1,16 @@
 package distributions
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"math"
+	"net/url"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +21,8 @@ import (
 type DistributionMirrors struct {
 	Distribution Distributor       `json:"distribution"`
 	Mirrors      []*mirrors.Mirror `json:"urls"`
+	// Mirrors are taken from the embedded backup snapshot, instead of the current mirror list
+	Stale bool `json:"stale,omitempty"`
 }
 
 func (d DistributionMirrors) String() string {
@@ -26,9 +33,11 @@ func (d *DistributionMirrors) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Distribution string            `json:"distribution"`
 		Mirrors      []*mirrors.Mirror `json:"urls"`
+		Stale        bool              `json:"stale,omitempty"`
 	}{
 		Distribution: d.Distribution.Name(),
 		Mirrors:      d.Mirrors,
+		Stale:        d.Stale,
 	})
 }
 
@@ -39,7 +48,11 @@ func (d *DistributionMirrors) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	if v["distribution"] != nil {
-		d.Distribution, err = ToDistribution(v["distribution"].(string))
+		distro, ok := v["distribution"].(string)
+		if !ok {
+			return fmt.Errorf("invalid distribution: %v", v["distribution"])
+		}
+		d.Distribution, err = ToDistribution(distro)
 		if err != nil {
 			return err
 		}
@@ -49,9 +62,82 @@ func (d *DistributionMirrors) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// Replaces Mirror list (don't care if empty or not)
-func (d *DistributionMirrors) UpdateMirrors(source mirrors.MirrorSource, filename string) {
-	mirrorsList := d.Distribution.GetMirrors(source, filename)
+// Replaces Mirror list (keeps the current list on error)
+func (d *DistributionMirrors) UpdateMirrors(source mirrors.MirrorSource, filename string) error {
+	return d.UpdateMirrorsContext(context.Background(), source, filename)
+}
+
+// Same as UpdateMirrors, but fetching is aborted when the context is done
+// (if the distribution supports it).
+// If an HTTP source can not be fetched, the embedded backup snapshot is used and the mirrors are marked as stale.
+func (d *DistributionMirrors) UpdateMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) error {
+	if source == mirrors.SourceBackup {
+		return d.useBackup()
+	}
+	var mirrorsList []mirrors.Mirror
+	var err error
+	if distro, ok := d.Distribution.(ContextDistributor); ok {
+		mirrorsList, err = distro.GetMirrorsContext(ctx, source, filename)
+	} else {
+		mirrorsList, err = d.Distribution.GetMirrors(source, filename)
+	}
+	if err == nil {
+		mirrorsList, err = d.keepMirrors(mirrorsList)
+	}
+	if err != nil {
+		// Fall back to the backup snapshot (unless interrupted)
+		if source == mirrors.SourceHTTP && !errors.Is(err, context.Canceled) {
+			log.Println("Error: Failed to fetch mirror list, using backup:", err)
+			if backupErr := d.useBackup(); backupErr == nil {
+				return nil
+			}
+		}
+		return err
+	}
+	d.setMirrors(mirrorsList)
+	d.Stale = false
+	return nil
+}
+
+// Replaces Mirror list with the embedded backup snapshot
+func (d *DistributionMirrors) useBackup() error {
+	mirrorsList, generated, err := BackupMirrors(d.Distribution)
+	if err == nil {
+		mirrorsList, err = d.keepMirrors(mirrorsList)
+	}
+	if err != nil {
+		return err
+	}
+	if generated.IsZero() {
+		log.Printf("Using curated backup mirror list of %v\n", d.Distribution.Name())
+	} else {
+		log.Printf("Using backup mirror list of %v from %v\n", d.Distribution.Name(), generated.Format("2006-01-02"))
+	}
+	d.setMirrors(mirrorsList)
+	d.Stale = true
+	return nil
+}
+
+// Removes the mirrors excluded by the distribution (see MirrorFilter).
+// Returns mirrors.ErrNoMirrors if all mirrors are excluded.
+func (d *DistributionMirrors) keepMirrors(mirrorsList []mirrors.Mirror) ([]mirrors.Mirror, error) {
+	filter, ok := d.Distribution.(MirrorFilter)
+	if !ok || len(mirrorsList) == 0 {
+		return mirrorsList, nil
+	}
+	kept := []mirrors.Mirror{}
+	for _, mirror := range mirrorsList {
+		if filter.KeepMirror(mirror) {
+			kept = append(kept, mirror)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return kept, nil
+}
+
+func (d *DistributionMirrors) setMirrors(mirrorsList []mirrors.Mirror) {
 	d.Mirrors = make([]*mirrors.Mirror, len(mirrorsList))
 	// Create shallow copy and assign to slice of pointers
 	for i, mirror := range mirrorsList {
@@ -60,53 +146,371 @@ func (d *DistributionMirrors) UpdateMirrors(source mirrors.MirrorSource, filenam
 	}
 }
 
-// TODO: Filter by host (1 host -> multiple mirrors (protocols))
-// TODO: Customize progress bar
+// Options for measuring mirror statistics
+type StatisticsOptions struct {
+	// Rounds of requests made to each mirror
+	Rounds int64
+	// Maximum number of mirrors measured at the same time (0 means no limit)
+	Workers int
+	// Maximum number of requests started per second (0 means no limit)
+	RateLimit float64
+	// Maximum number of requests made to the same host at the same time (0 means no limit)
+	PerHost int
+	// File requested to measure response time and validate the mirrors (empty path means the mirror URL, with any content).
+	// Mirrors that respond without serving it are counted as failed (broken).
+	Canary mirrors.Canary
+	// File used to measure download speed, relative to the mirror URL (empty means no speed test)
+	SpeedPath string
+	// Maximum bytes downloaded for each speed test
+	SpeedBytes int64
+	// Maximum time spent downloading for each speed test
+	SpeedDuration time.Duration
+	// Number of reachable mirrors with the lowest response time that are speed tested (0 means all).
+	// Speed tests run one at a time after all rounds, so that they do not share the bandwidth.
+	SpeedTop int
+	// APT Release file used to find the freshness of mirrors, relative to the mirror URL (empty means no freshness check)
+	ReleasePath string
+	// Upstream archive, also used as reference for the freshness of mirrors (optional)
+	UpstreamURL string
+}
+
 func (d *DistributionMirrors) UpdateMirrorStatistics(rounds int64) {
-	// Keep statistics from all rounds
-	allTimes := make([][]time.Duration, rounds)
-	for round := range allTimes {
-		allTimes[round] = make([]time.Duration, len(d.Mirrors))
+	d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: rounds})
+}
+
+// Same as UpdateMirrorStatistics, but all in-flight requests are aborted when the context is done.
+// Statistics are kept for the mirrors that finished before that, and the context error is returned.
+// TODO: Customize progress bar
+func (d *DistributionMirrors) UpdateMirrorStatisticsContext(ctx context.Context, opts StatisticsOptions) error {
+	// Keep statistics from all (finished) rounds
+	accum := make([]time.Duration, len(d.Mirrors))
+	finished := make([]int64, len(d.Mirrors))
+	probes := make([]int64, len(d.Mirrors))
+	failures := make([]int64, len(d.Mirrors))
+	accumPing := make([]time.Duration, len(d.Mirrors))
+	finishedPing := make([]int64, len(d.Mirrors))
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics == nil {
+			mirror.Statistics = &mirrors.MirrorStatistics{}
+		}
 	}
 
+	// Limit number of parallel requests
+	workers := opts.Workers
+	if workers <= 0 || workers > len(d.Mirrors) {
+		workers = len(d.Mirrors)
+	}
+	// Limit rate of requests
+	var limiter <-chan time.Time
+	if opts.RateLimit > 0 {
+		// Rates above one request per nanosecond are not limited further (the ticker needs a positive interval)
+		interval := time.Duration(float64(time.Second) / opts.RateLimit)
+		if interval < time.Nanosecond {
+			interval = time.Nanosecond
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+	// Limit parallel requests per host (same host can be listed with multiple protocols)
+	hosts := newHostLimiter(opts.PerHost)
+
 	// Run for each round
-	for round := int64(0); round < rounds; round++ {
+	for round := int64(0); round < opts.Rounds && ctx.Err() == nil; round++ {
 		fmt.Fprintf(os.Stderr, "Round %v\n", round)
-		// Create wait group for all goroutines
+		// Create wait group for all workers
 		var wg sync.WaitGroup
-		wg.Add(len(d.Mirrors))
+		wg.Add(workers)
 
 		// Create progress bar
 		bar := progressbar.Default(int64(len(d.Mirrors)), "Mirrors")
 
-		// For each mirror create a goroutine for parallel requests
-		for i, mirror := range d.Mirrors {
-			if mirror.Statistics == nil {
-				mirror.Statistics = &mirrors.MirrorStatistics{}
+		// Create a pool of goroutines for parallel requests
+		jobs := make(chan int)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					mirror := d.Mirrors[i]
+					release, ok := hosts.acquire(ctx, mirror.URL.Hostname())
+					if !ok {
+						continue
+					}
+					// Make request and update mirror statistics
+					result := mirror.ProbeCanaryContext(ctx, opts.Canary)
+					// Measure round-trip time (unprivileged) of reachable mirrors
+					if result.OK() {
+						rtt, err := mirror.GetLatencyContext(ctx)
+						if err == nil {
+							accumPing[i] += rtt
+							finishedPing[i]++
+						}
+					}
+					// Find freshness of reachable mirrors (only once), from the canary if it was an InRelease file,
+					// or else with a separate request for the Release file
+					if result.OK() && round == 0 && len(opts.ReleasePath) != 0 {
+						if !result.ReleaseDate.IsZero() {
+							mirror.Statistics.LastUpdate = result.ReleaseDate
+						} else if date, err := mirror.GetReleaseDateContext(ctx, opts.ReleasePath); err == nil {
+							mirror.Statistics.LastUpdate = date
+						}
+					}
+					release()
+					bar.Add(1)
+					// Discard probes that were aborted before finishing
+					if !result.OK() && ctx.Err() != nil {
+						continue
+					}
+					// fmt.Fprintf(os.Stderr, "Mirror %v: Country: %v, URL: %v, Result: %v\n", i, mirror.Country, mirror.URL, result)
+					mirror.Statistics.Probe = result
+					mirror.Statistics.Timing = result.Timing
+					probes[i]++
+					// Failures are counted separately, and not included in the averages
+					if !result.OK() {
+						failures[i]++
+						continue
+					}
+					mirror.Statistics.ResponseTimeHTTP = result.Timing.Total
+					accum[i] += result.Timing.Total
+					finished[i]++
+				}
+			}()
+		}
+		// Send mirrors to workers (stop early if context is done)
+	send:
+		for i := range d.Mirrors {
+			if limiter != nil {
+				select {
+				case <-limiter:
+				case <-ctx.Done():
+					break send
+				}
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				break send
 			}
-			// Make request and update mirror statistics
-			go func(round int64, i int, mirror *mirrors.Mirror) {
-				totalTime := mirror.GetTime()
-				// fmt.Fprintf(os.Stderr, "Mirror %v: Country: %v, URL: %v, Time: %v\n", i, mirror.Country, mirror.URL, totalTime)
-				d.Mirrors[i].Statistics.ResponseTimeHTTP = totalTime
-				allTimes[round][i] = totalTime
-				wg.Done()
-				bar.Add(1)
-			}(round, i, mirror)
 		}
+		close(jobs)
 		wg.Wait()
 	}
 
+	// Calculate sync lag
+	if len(opts.ReleasePath) != 0 {
+		d.updateSyncLag(ctx, opts)
+	}
+
 	// Calculate averages
-	accum := make([]time.Duration, len(d.Mirrors))
-	for i := range allTimes[0] {
-		for round := range allTimes {
-			accum[i] += allTimes[round][i]
+	for i, mirror := range d.Mirrors {
+		if finishedPing[i] != 0 {
+			mirror.Statistics.ResponseTimePing = time.Duration(int64(accumPing[i]) / finishedPing[i])
+		}
+		mirror.Statistics.Probes = probes[i]
+		mirror.Statistics.Failures = failures[i]
+		if finished[i] == 0 {
+			mirror.Statistics.ResponseTimeHTTP = 0
+			mirror.Statistics.AvgResponseTimeHTTP = 0
+			// Mirrors that could not be probed before the context was done are counted as failed
+			if probes[i] == 0 && ctx.Err() != nil {
+				mirror.Statistics.Probes = 1
+				mirror.Statistics.Failures = 1
+				mirror.Statistics.Probe = mirrors.NewProbeResult(ctx.Err())
+			}
+			continue
+		}
+		mirror.Statistics.AvgResponseTimeHTTP = time.Duration(int64(accum[i]) / finished[i])
+		// fmt.Fprintf(os.Stderr, "Mirror %v: Country: %v, URL: %v, Time: %v, Avg Time: %v, Accum Time: %v\n", i, mirror.Country, mirror.URL, mirror.Statistics.ResponseTimeHTTP, mirror.Statistics.AvgResponseTimeHTTP, accum[i])
+	}
+
+	// Measure download speed
+	if len(opts.SpeedPath) != 0 {
+		d.updateSpeeds(ctx, opts)
+	}
+	return ctx.Err()
+}
+
+// Measures the download speed of the reachable mirrors with the lowest response time, one at a time
+func (d *DistributionMirrors) updateSpeeds(ctx context.Context, opts StatisticsOptions) {
+	candidates := []*mirrors.Mirror{}
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics.Probes != 0 && mirror.Statistics.Reachable() {
+			candidates = append(candidates, mirror)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lessBy(SortLatency, candidates[i], candidates[j])
+	})
+	if opts.SpeedTop > 0 && opts.SpeedTop < len(candidates) {
+		candidates = candidates[:opts.SpeedTop]
+	}
+	fmt.Fprintf(os.Stderr, "Speed Test\n")
+	bar := progressbar.Default(int64(len(candidates)), "Mirrors")
+	for _, mirror := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		speed, err := mirror.GetSpeedContext(ctx, opts.SpeedPath, opts.SpeedBytes, opts.SpeedDuration)
+		if err == nil {
+			mirror.Statistics.SpeedBps = speed
+		}
+		bar.Add(1)
+	}
+}
+
+// Calculates how far behind each mirror is, compared to the most recent release found on
+// the upstream archive or any of the mirrors
+func (d *DistributionMirrors) updateSyncLag(ctx context.Context, opts StatisticsOptions) {
+	latest := time.Time{}
+	if len(opts.UpstreamURL) != 0 {
+		upstreamURL, err := url.Parse(opts.UpstreamURL)
+		if err == nil {
+			upstream := mirrors.Mirror{URL: upstreamURL}
+			if date, err := upstream.GetReleaseDateContext(ctx, opts.ReleasePath); err == nil {
+				latest = date
+			}
+		}
+	}
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics.LastUpdate.After(latest) {
+			latest = mirror.Statistics.LastUpdate
+		}
+	}
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics.LastUpdate.IsZero() {
+			continue
+		}
+		mirror.Statistics.SyncLag = latest.Sub(mirror.Statistics.LastUpdate)
+	}
+}
+
+// Calculates the rank score of each reachable mirror, by blending the score reported by the mirror status
+// (e.g. Arch mirror status) with the measured response time, similar to what reflector does.
+// Both are taken relative to the best mirror (1 is the best), and weight is the share of the status score (0-1).
+// Mirrors without a status score are given the worst status score.
+func (d *DistributionMirrors) UpdateRankScores(weight float64) {
+	bestTime, bestScore, worstScore := time.Duration(math.MaxInt64), math.MaxFloat64, 0.0
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics == nil || !mirror.Statistics.Reachable() {
+			continue
+		}
+		if mirror.Statistics.AvgResponseTimeHTTP < bestTime {
+			bestTime = mirror.Statistics.AvgResponseTimeHTTP
+		}
+		if mirror.Score > 0 {
+			bestScore = math.Min(bestScore, mirror.Score)
+			worstScore = math.Max(worstScore, mirror.Score)
+		}
+	}
+	for _, mirror := range d.Mirrors {
+		if mirror.Statistics == nil {
+			continue
+		}
+		if !mirror.Statistics.Reachable() {
+			mirror.Statistics.RankScore = 0
+			continue
 		}
-		d.Mirrors[i].Statistics.AvgResponseTimeHTTP = time.Duration(int64(accum[i]) / rounds)
-		// fmt.Fprintf(os.Stderr, "Mirror %v: Country: %v, URL: %v, Time: %v, Avg Time: %v, Accum Time: %v\n", i, d.Mirrors[i].Country, d.Mirrors[i].URL, d.Mirrors[i].Statistics.ResponseTimeHTTP, d.Mirrors[i].Statistics.AvgResponseTimeHTTP, accum[i])
+		relativeTime := float64(mirror.Statistics.AvgResponseTimeHTTP+1) / float64(bestTime+1)
+		relativeScore := 1.0
+		if worstScore > 0 {
+			score := mirror.Score
+			if score <= 0 {
+				score = worstScore
+			}
+			relativeScore = score / bestScore
+		}
+		mirror.Statistics.RankScore = weight*relativeScore + (1-weight)*relativeTime
+	}
+}
+
+// Removes the mirrors that are not kept by the filter
+func (d *DistributionMirrors) FilterMirrors(keep func(mirror *mirrors.Mirror) bool) {
+	filtered := make([]*mirrors.Mirror, 0, len(d.Mirrors))
+	for _, mirror := range d.Mirrors {
+		if keep(mirror) {
+			filtered = append(filtered, mirror)
+		}
+	}
+	d.Mirrors = filtered
+}
+
+// Filter that keeps mirrors with known freshness lagging at most maxLag, and mirrors with unknown freshness
+func MaxSyncLag(maxLag time.Duration) func(mirror *mirrors.Mirror) bool {
+	return func(mirror *mirrors.Mirror) bool {
+		if mirror.Statistics == nil || mirror.Statistics.LastUpdate.IsZero() {
+			return true
+		}
+		return mirror.Statistics.SyncLag <= maxLag
+	}
+}
+
+// Limits the number of parallel requests made to the same host
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, hosts: map[string]chan struct{}{}}
+}
+
+// Blocks until a request to the host is allowed, returns false if the context is done first
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), ok bool) {
+	if l.limit <= 0 {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	sem, found := l.hosts[host]
+	if !found {
+		sem = make(chan struct{}, l.limit)
+		l.hosts[host] = sem
 	}
+	l.mu.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
 
+// Criteria for ranking mirrors
+type SortKey int
+
+const (
+	// Lowest average HTTP response time first
+	SortLatency SortKey = iota
+	// Highest download speed first (mirrors without speed measurement last)
+	SortSpeed
+	// Lowest rank score first (see UpdateRankScores)
+	SortScore
+)
+
+func (k SortKey) String() string {
+	switch k {
+	case SortLatency:
+		return "latency"
+	case SortSpeed:
+		return "speed"
+	case SortScore:
+		return "score"
+	default:
+		return fmt.Sprintf("%d", k)
+	}
+}
+
+func ToSortKey(key string) (SortKey, error) {
+	switch strings.ToLower(key) {
+	case "latency":
+		return SortLatency, nil
+	case "speed":
+		return SortSpeed, nil
+	case "score":
+		return SortScore, nil
+	default:
+		return -1, fmt.Errorf("unsupported sort key: %v", key)
+	}
 }
 
 func (d DistributionMirrors) Len() int {
@@ -114,30 +518,67 @@ func (d DistributionMirrors) Len() int {
 }
 
 func (d DistributionMirrors) Less(i, j int) bool {
-	return d.Mirrors[i].Statistics.AvgResponseTimeHTTP < d.Mirrors[j].Statistics.AvgResponseTimeHTTP
+	return lessBy(SortLatency, d.Mirrors[i], d.Mirrors[j])
 }
 
 func (d DistributionMirrors) Swap(i, j int) {
 	d.Mirrors[i], d.Mirrors[j] = d.Mirrors[j], d.Mirrors[i]
 }
 
-// TODO: Sort based on other factors
+// Reports whether mirror a ranks before mirror b based on the key (unreachable mirrors and mirrors without statistics rank last)
+func lessBy(key SortKey, a, b *mirrors.Mirror) bool {
+	if a.Statistics == nil || b.Statistics == nil {
+		return a.Statistics != nil
+	}
+	if a.Statistics.Reachable() != b.Statistics.Reachable() {
+		return a.Statistics.Reachable()
+	}
+	switch key {
+	case SortSpeed:
+		if a.Statistics.SpeedBps != b.Statistics.SpeedBps {
+			return a.Statistics.SpeedBps > b.Statistics.SpeedBps
+		}
+	case SortScore:
+		if a.Statistics.RankScore != b.Statistics.RankScore {
+			if a.Statistics.RankScore == 0 || b.Statistics.RankScore == 0 {
+				return b.Statistics.RankScore == 0
+			}
+			return a.Statistics.RankScore < b.Statistics.RankScore
+		}
+	}
+	return a.Statistics.AvgResponseTimeHTTP < b.Statistics.AvgResponseTimeHTTP
+}
+
 func (d *DistributionMirrors) SortMirrors() {
 	sort.Sort(d)
 }
 
-// TODO: Find based on other factors
+// Sorts mirrors based on the given criteria (ties are ranked by response time)
+func (d *DistributionMirrors) SortMirrorsBy(key SortKey) {
+	sort.SliceStable(d.Mirrors, func(i, j int) bool {
+		return lessBy(key, d.Mirrors[i], d.Mirrors[j])
+	})
+}
+
+// Returns an empty Mirror if no mirror has statistics (see BestMirrorBy)
 func (d *DistributionMirrors) BestMirror() mirrors.Mirror {
-	bestTime := time.Duration(math.MaxInt64)
-	bestMirror := mirrors.Mirror{}
+	bestMirror, _ := d.BestMirrorBy(SortLatency)
+	return bestMirror
+}
+
+// Finds the best mirror based on the given criteria (ErrNoMirrors if no mirror has statistics)
+func (d *DistributionMirrors) BestMirrorBy(key SortKey) (mirrors.Mirror, error) {
+	var bestMirror *mirrors.Mirror
 	for _, mirror := range d.Mirrors {
 		if mirror.Statistics == nil {
 			continue
 		}
-		if mirror.Statistics.AvgResponseTimeHTTP < bestTime {
-			bestTime = mirror.Statistics.AvgResponseTimeHTTP
-			bestMirror = *mirror
+		if bestMirror == nil || lessBy(key, mirror, bestMirror) {
+			bestMirror = mirror
 		}
 	}
-	return bestMirror
+	if bestMirror == nil {
+		return mirrors.Mirror{}, mirrors.ErrNoMirrors
+	}
+	return *bestMirror, nil
 }
@@ -0,0 +1,325 @@
+package distributions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+// Creates a test server that keeps track of the maximum parallel requests it served
+func newConcurrencyServer(delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	current, max := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current++
+		if current > max {
+			max = current
+		}
+		mu.Unlock()
+		time.Sleep(delay)
+		mu.Lock()
+		current--
+		mu.Unlock()
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return max
+	}
+}
+
+func newTestMirrors(serverURL string, count int) *DistributionMirrors {
+	u, _ := url.Parse(serverURL)
+	d := &DistributionMirrors{Distribution: CustomDistributor{CustomName: "Test"}}
+	for i := 0; i < count; i++ {
+		d.Mirrors = append(d.Mirrors, &mirrors.Mirror{URL: u, Protocol: mirrors.ProtoHTTP})
+	}
+	return d
+}
+
+// Marks a test mirror as failing all probes
+func unreachable(mirror *mirrors.Mirror) *mirrors.Mirror {
+	mirror.Statistics = &mirrors.MirrorStatistics{Probes: 1, Failures: 1, Probe: mirrors.ProbeResult{Status: mirrors.ProbeTimeout}}
+	return mirror
+}
+
+func TestUpdateMirrorStatisticsWorkers(t *testing.T) {
+	server, maxParallel := newConcurrencyServer(50 * time.Millisecond)
+	defer server.Close()
+	d := newTestMirrors(server.URL, 8)
+
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 1, Workers: 2})
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxParallel(), 2)
+	for _, m := range d.Mirrors {
+		assert.Less(t, m.Statistics.AvgResponseTimeHTTP, mirrors.HTTP_TIMEOUT*time.Second)
+		assert.Greater(t, m.Statistics.ResponseTimePing, time.Duration(0))
+	}
+}
+
+func TestUpdateMirrorStatisticsPerHost(t *testing.T) {
+	server, maxParallel := newConcurrencyServer(50 * time.Millisecond)
+	defer server.Close()
+	d := newTestMirrors(server.URL, 4)
+
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 1, PerHost: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, maxParallel())
+}
+
+func TestUpdateMirrorStatisticsRateLimit(t *testing.T) {
+	server, _ := newConcurrencyServer(0)
+	defer server.Close()
+	d := newTestMirrors(server.URL, 2)
+
+	// Rates too high for a ticker interval are not an error
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 1, RateLimit: 1e12})
+	assert.NoError(t, err)
+	for _, m := range d.Mirrors {
+		assert.True(t, m.Statistics.Reachable())
+	}
+}
+
+func TestUpdateMirrorStatisticsDeadline(t *testing.T) {
+	server, _ := newConcurrencyServer(50 * time.Millisecond)
+	defer server.Close()
+	d := newTestMirrors(server.URL, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	err := d.UpdateMirrorStatisticsContext(ctx, StatisticsOptions{Rounds: 1, Workers: 1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	measured := 0
+	for _, m := range d.Mirrors {
+		if m.Statistics.Reachable() {
+			assert.Greater(t, m.Statistics.AvgResponseTimeHTTP, time.Duration(0))
+			measured++
+		} else {
+			// Mirrors that were not measured in time are reported as timed out, not as slow
+			assert.Equal(t, mirrors.ProbeTimeout, m.Statistics.Probe.Status)
+			assert.Equal(t, time.Duration(0), m.Statistics.AvgResponseTimeHTTP)
+		}
+	}
+	assert.Greater(t, measured, 0)
+	assert.Less(t, measured, len(d.Mirrors))
+}
+
+func TestUpdateMirrorStatisticsSpeed(t *testing.T) {
+	var mu sync.Mutex
+	downloads, current, max := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/speed.bin" {
+			return
+		}
+		mu.Lock()
+		downloads++
+		current++
+		if current > max {
+			max = current
+		}
+		mu.Unlock()
+		w.Write(make([]byte, 64*1024))
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		current--
+		mu.Unlock()
+	}))
+	defer server.Close()
+	d := newTestMirrors(server.URL, 5)
+
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{
+		Rounds: 2, SpeedPath: "speed.bin", SpeedBytes: 1024 * 1024, SpeedDuration: time.Second, SpeedTop: 3,
+	})
+	assert.NoError(t, err)
+	// Only the top mirrors are speed tested, once and one at a time
+	assert.Equal(t, 3, downloads)
+	assert.Equal(t, 1, max)
+	measured := 0
+	for _, m := range d.Mirrors {
+		if m.Statistics.SpeedBps > 0 {
+			measured++
+		}
+	}
+	assert.Equal(t, 3, measured)
+}
+
+func TestUpdateMirrorStatisticsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	d := newTestMirrors(server.URL, 2)
+
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 3})
+	assert.NoError(t, err)
+	for _, m := range d.Mirrors {
+		assert.False(t, m.Statistics.Reachable())
+		assert.Equal(t, int64(3), m.Statistics.Probes)
+		assert.Equal(t, int64(3), m.Statistics.Failures)
+		assert.Equal(t, mirrors.ProbeHTTPError, m.Statistics.Probe.Status)
+		assert.Equal(t, http.StatusServiceUnavailable, m.Statistics.Probe.StatusCode)
+		assert.Equal(t, time.Duration(0), m.Statistics.AvgResponseTimeHTTP)
+	}
+	assert.Empty(t, d.usableMirrors(0, "http"))
+
+	var b strings.Builder
+	assert.NoError(t, d.WriteStdout(&b))
+	assert.Contains(t, b.String(), " unreachable ")
+}
+
+func TestUpdateMirrorStatisticsCanary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/debian/dists/bookworm/InRelease" {
+			fmt.Fprintln(w, mirrors.SIGNATURE_PGP_SIGNED)
+			return
+		}
+		fmt.Fprintln(w, "<html><body>Parked domain</body></html>")
+	}))
+	defer server.Close()
+	d := &DistributionMirrors{Distribution: Debian{Suite: "bookworm"}}
+	for _, path := range []string{"/parked/", "/debian/"} {
+		u, _ := url.Parse(server.URL + path)
+		d.Mirrors = append(d.Mirrors, &mirrors.Mirror{URL: u})
+	}
+
+	canary := d.Distribution.(Validator).Canary()
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{Rounds: 1, Canary: canary})
+	assert.NoError(t, err)
+	assert.Equal(t, mirrors.ProbeBroken, d.Mirrors[0].Statistics.Probe.Status)
+	assert.True(t, d.Mirrors[1].Statistics.Probe.OK())
+
+	d.SortMirrorsBy(SortLatency)
+	assert.Equal(t, "/debian/", d.Mirrors[0].URL.Path)
+	assert.Len(t, d.usableMirrors(0, "http"), 1)
+	var b strings.Builder
+	assert.NoError(t, d.WriteStdout(&b))
+	assert.Contains(t, b.String(), " broken ")
+}
+
+func TestDistributionCanaries(t *testing.T) {
+	tests := []struct {
+		distro   Validator
+		expected mirrors.Canary
+	}{
+		{Ubuntu{}, mirrors.Canary{Path: "dists/noble/InRelease", Signature: mirrors.SIGNATURE_PGP_SIGNED, ReleaseDate: true}},
+		{Arch{}, mirrors.Canary{Path: "core/os/x86_64/core.db", Signature: mirrors.SIGNATURE_GZIP}},
+		{Alpine{Branch: "3.20"}, mirrors.Canary{Path: "v3.20/main/x86_64/APKINDEX.tar.gz", Signature: mirrors.SIGNATURE_GZIP}},
+		{Rocky{Arch: "aarch64"}, mirrors.Canary{Path: "BaseOS/aarch64/os/repodata/repomd.xml", Signature: mirrors.SIGNATURE_XML}},
+		{Fedora{}, mirrors.Canary{Path: "repodata/repomd.xml", Signature: mirrors.SIGNATURE_XML}},
+		{Gentoo{}, mirrors.Canary{Path: "distfiles/layout.conf", Signature: "[structure]"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.distro.Canary())
+	}
+}
+
+func TestSortMirrorsBy(t *testing.T) {
+	newMirror := func(host string, latency time.Duration, speed float64) *mirrors.Mirror {
+		return &mirrors.Mirror{
+			URL:        &url.URL{Scheme: "http", Host: host},
+			Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: latency, SpeedBps: speed},
+		}
+	}
+	d := &DistributionMirrors{Mirrors: []*mirrors.Mirror{
+		newMirror("a", 30*time.Millisecond, 1000),
+		newMirror("b", 10*time.Millisecond, 0),
+		newMirror("c", 20*time.Millisecond, 5000),
+	}}
+	hosts := func() []string {
+		h := []string{}
+		for _, m := range d.Mirrors {
+			h = append(h, m.URL.Host)
+		}
+		return h
+	}
+
+	d.SortMirrorsBy(SortLatency)
+	assert.Equal(t, []string{"b", "c", "a"}, hosts())
+	d.SortMirrorsBy(SortSpeed)
+	assert.Equal(t, []string{"c", "a", "b"}, hosts())
+	best, err := d.BestMirrorBy(SortSpeed)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", best.URL.Host)
+	best, err = d.BestMirrorBy(SortLatency)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", best.URL.Host)
+}
+
+func TestUpdateMirrorStatisticsReleaseDateFromCanary(t *testing.T) {
+	var mu sync.Mutex
+	releaseRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debian/" + InReleasePath("stable"):
+			fmt.Fprintf(w, "%v\nHash: SHA512\n\nOrigin: Debian\nDate: Sat, 12 Oct 2024 09:00:00 UTC\n", mirrors.SIGNATURE_PGP_SIGNED)
+		case "/debian/" + ReleasePath("stable"):
+			mu.Lock()
+			releaseRequests++
+			mu.Unlock()
+		}
+	}))
+	defer server.Close()
+	d := &DistributionMirrors{Distribution: Debian{Suite: "stable"}}
+	u, _ := url.Parse(server.URL + "/debian/")
+	d.Mirrors = append(d.Mirrors, &mirrors.Mirror{URL: u})
+
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{
+		Rounds:      1,
+		Canary:      d.Distribution.(Validator).Canary(),
+		ReleasePath: ReleasePath("stable"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 10, 12, 9, 0, 0, 0, time.UTC), d.Mirrors[0].Statistics.LastUpdate)
+	assert.Equal(t, 0, releaseRequests)
+}
+
+func TestUpdateMirrorStatisticsSyncLag(t *testing.T) {
+	newReleaseServer := func(date string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/debian/"+ReleasePath("stable") {
+				fmt.Fprintf(w, "Origin: Debian\nSuite: stable\nDate: %v\n", date)
+			}
+		}))
+	}
+	upstream := newReleaseServer("Sat, 12 Oct 2024 12:00:00 UTC")
+	defer upstream.Close()
+	fresh := newReleaseServer("Sat, 12 Oct 2024 09:00:00 UTC")
+	defer fresh.Close()
+	stale := newReleaseServer("Wed, 09 Oct 2024 12:00:00 UTC")
+	defer stale.Close()
+
+	d := &DistributionMirrors{Distribution: Debian{}}
+	for _, server := range []*httptest.Server{fresh, stale} {
+		u, _ := url.Parse(server.URL + "/debian/")
+		d.Mirrors = append(d.Mirrors, &mirrors.Mirror{URL: u})
+	}
+	err := d.UpdateMirrorStatisticsContext(context.Background(), StatisticsOptions{
+		Rounds:      1,
+		ReleasePath: ReleasePath("stable"),
+		UpstreamURL: upstream.URL + "/debian/",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Hour, d.Mirrors[0].Statistics.SyncLag)
+	assert.Equal(t, 72*time.Hour, d.Mirrors[1].Statistics.SyncLag)
+
+	d.FilterMirrors(MaxSyncLag(6 * time.Hour))
+	assert.Len(t, d.Mirrors, 1)
+	assert.Equal(t, fresh.URL+"/debian/", d.Mirrors[0].URL.String())
+
+	// No best mirror is left if all mirrors are too far behind
+	d.FilterMirrors(MaxSyncLag(time.Hour))
+	assert.Empty(t, d.Mirrors)
+	_, err = d.BestMirrorBy(SortLatency)
+	assert.ErrorIs(t, err, mirrors.ErrNoMirrors)
+}
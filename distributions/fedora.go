@@ -0,0 +1,96 @@
+package distributions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+const (
+	FEDORA_METALINK_URL    = "https://mirrors.fedoraproject.org/metalink?repo=fedora-%v&arch=%v"
+	FEDORA_METALINK_FILE   = "repodata/repomd.xml"
+	FEDORA_DEFAULT_RELEASE = "43"
+	FEDORA_DEFAULT_ARCH    = "x86_64"
+)
+
+type Fedora struct {
+	// Release version (e.g. "43", "rawhide") of the repository
+	Version string
+	// Base architecture (e.g. "x86_64", "aarch64") of the repository
+	Arch string
+}
+
+func (fed Fedora) Name() string { return "Fedora" }
+
+func (fed Fedora) WithRelease(release string) Distributor {
+	fed.Version = release
+	return fed
+}
+
+func (fed Fedora) Release() string {
+	if len(fed.Version) == 0 {
+		return FEDORA_DEFAULT_RELEASE
+	}
+	return fed.Version
+}
+
+func (fed Fedora) WithArchitecture(arch string) Distributor {
+	fed.Arch = arch
+	return fed
+}
+
+func (fed Fedora) Architecture() string {
+	if len(fed.Arch) == 0 {
+		return FEDORA_DEFAULT_ARCH
+	}
+	return fed.Arch
+}
+
+// Repository metadata, as listed in the metalink
+func (fed Fedora) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: FEDORA_METALINK_FILE, Signature: mirrors.SIGNATURE_XML}
+}
+
+// URL of the metalink with the mirrors of the release and architecture
+func (fed Fedora) MetalinkURL() string {
+	return fmt.Sprintf(FEDORA_METALINK_URL, fed.Release(), fed.Architecture())
+}
+
+func (fed Fedora) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return fed.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (fed Fedora) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		return FetchFedoraMirrorsContext(ctx, fed.MetalinkURL())
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
+
+func FetchFedoraMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchFedoraMirrorsContext(context.Background(), URL)
+}
+
+// Same as FetchFedoraMirrors, but the request is aborted when the context is done.
+func FetchFedoraMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	mirrorsList, err := ParseMetalink(resp, FEDORA_METALINK_FILE)
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
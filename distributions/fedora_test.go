@@ -0,0 +1,48 @@
+package distributions
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchFedoraMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/fedora.metalink")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchFedoraMirrors(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 6)
+	first := mirrorsList[0]
+	assert.Equal(t, "https://ftp.fau.de/fedora/linux/releases/43/Everything/x86_64/os/", first.URL.String())
+	assert.Equal(t, mirrors.ProtoHTTPS, first.Protocol)
+	assert.Equal(t, "DE", first.CountryCode)
+	assert.Equal(t, "Germany", first.Country)
+	assert.Equal(t, 100, first.Preference)
+	assert.Equal(t, mirrors.ProtoRSYNC, mirrorsList[2].Protocol)
+	assert.Equal(t, 95, mirrorsList[5].Preference)
+}
+
+func TestFetchFedoraMirrorsInvalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Not a metalink</body></html>"))
+	}))
+	defer server.Close()
+
+	_, err := FetchFedoraMirrors(server.URL)
+	var parseErr *mirrors.ParseError
+	assert.True(t, errors.As(err, &parseErr))
+}
+
+func TestFedoraMetalinkURL(t *testing.T) {
+	fedora := Fedora{}.WithRelease("rawhide").(Fedora)
+	fedora.Arch = "aarch64"
+	assert.Equal(t, "https://mirrors.fedoraproject.org/metalink?repo=fedora-rawhide&arch=aarch64", fedora.MetalinkURL())
+	assert.Equal(t, "https://mirrors.fedoraproject.org/metalink?repo=fedora-43&arch=x86_64", Fedora{}.MetalinkURL())
+}
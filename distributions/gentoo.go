@@ -0,0 +1,145 @@
+package distributions
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+const (
+	GENTOO_MIRRORS_URL = "https://api.gentoo.org/mirrors/distfiles.xml"
+)
+
+type Gentoo struct{}
+
+// Mirror list of distfiles.xml (as used by mirrorselect)
+type gentooMirrors struct {
+	Groups []struct {
+		Region      string `xml:"region,attr"`
+		Country     string `xml:"country,attr"`
+		CountryName string `xml:"countryname,attr"`
+		Mirrors     []struct {
+			Name string `xml:"name"`
+			URIs []struct {
+				URI      string `xml:",chardata"`
+				Protocol string `xml:"protocol,attr"`
+				IPv4     string `xml:"ipv4,attr"`
+				IPv6     string `xml:"ipv6,attr"`
+				Partial  string `xml:"partial,attr"`
+			} `xml:"uri"`
+		} `xml:"mirror"`
+	} `xml:"mirrorgroup"`
+}
+
+func (gen Gentoo) Name() string { return "Gentoo" }
+
+// Layout of the distfiles directory (GLEP 75)
+func (gen Gentoo) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: "distfiles/layout.conf", Signature: "[structure]"}
+}
+
+func (gen Gentoo) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return gen.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (gen Gentoo) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		return FetchGentooMirrorsContext(ctx, GENTOO_MIRRORS_URL)
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
+
+func FetchGentooMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchGentooMirrorsContext(context.Background(), URL)
+}
+
+// Same as FetchGentooMirrors, but the request is aborted when the context is done.
+func FetchGentooMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	// Make request
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	// Parse XML mirror list
+	var doc gentooMirrors
+	if err := xml.Unmarshal(resp, &doc); err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, group := range doc.Groups {
+		for _, mirror := range group.Mirrors {
+			// One mirror for each protocol
+			for _, uri := range mirror.URIs {
+				urlStr, err := url.Parse(strings.TrimSpace(uri.URI))
+				if err != nil {
+					log.Println("Error: Failed to parse URL:", err)
+					continue
+				}
+				protocol := uri.Protocol
+				if len(protocol) == 0 {
+					protocol = urlStr.Scheme
+				}
+				proto, err := mirrors.ToProtocol(protocol)
+				if err != nil {
+					log.Println("Error: Failed to parse URL:", err)
+					continue
+				}
+				mirrorsList = append(mirrorsList, mirrors.Mirror{
+					Country:     group.CountryName,
+					CountryCode: group.Country,
+					Region:      group.Region,
+					URL:         urlStr,
+					Protocol:    proto,
+					IPv4:        uri.IPv4 == "y",
+					IPv6:        uri.IPv6 == "y",
+					Partial:     uri.Partial == "y",
+				})
+			}
+		}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Options for make.conf output
+type MakeConfOptions struct {
+	// Number of mirrors to use, in ranked order (default: only the best)
+	Top int
+}
+
+// Writes the best mirrors as the GENTOO_MIRRORS variable of make.conf (/etc/portage/make.conf)
+func (d *DistributionMirrors) WriteGentooMirrors(w io.Writer, opts MakeConfOptions) error {
+	if _, ok := d.Distribution.(Gentoo); !ok {
+		return fmt.Errorf("GENTOO_MIRRORS is not supported for %v", d.Distribution.Name())
+	}
+	if opts.Top <= 0 {
+		opts.Top = 1
+	}
+	// Portage fetches distfiles with wget, rsync mirrors are only used to sync the repository
+	best := d.usableMirrors(opts.Top, "http", "https", "ftp")
+	if len(best) == 0 {
+		return errors.New("no reachable HTTP or FTP mirror")
+	}
+	uris := make([]string, len(best))
+	for i, mirror := range best {
+		uris[i] = mirror.URL.String()
+	}
+	_, err := fmt.Fprintf(w, "GENTOO_MIRRORS=\"%v\"\n", strings.Join(uris, " "))
+	return err
+}
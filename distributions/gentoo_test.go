@@ -0,0 +1,62 @@
+package distributions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchGentooMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/gentoo.xml")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchGentooMirrors(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 5)
+	first := mirrorsList[0]
+	assert.Equal(t, "http://ftp.fau.de/gentoo", first.URL.String())
+	assert.Equal(t, mirrors.ProtoHTTP, first.Protocol)
+	assert.Equal(t, "Germany", first.Country)
+	assert.Equal(t, "DE", first.CountryCode)
+	assert.Equal(t, "Europe", first.Region)
+	assert.True(t, first.IPv4 && first.IPv6)
+	assert.False(t, first.Partial)
+	assert.Equal(t, mirrors.ProtoRSYNC, mirrorsList[2].Protocol)
+	partial := mirrorsList[3]
+	assert.Equal(t, mirrors.ProtoFTP, partial.Protocol)
+	assert.True(t, partial.Partial)
+	assert.False(t, partial.IPv6)
+	assert.Equal(t, "North America", mirrorsList[4].Region)
+}
+
+func TestWriteGentooMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/gentoo.xml")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchGentooMirrors(server.URL)
+	assert.NoError(t, err)
+	d := &DistributionMirrors{Distribution: Gentoo{}}
+	for i := range mirrorsList {
+		mirrorsList[i].Statistics = &mirrors.MirrorStatistics{AvgResponseTimeHTTP: time.Millisecond}
+		d.Mirrors = append(d.Mirrors, &mirrorsList[i])
+	}
+	// Partial and rsync mirrors are skipped
+	d.Mirrors[0], d.Mirrors[3] = d.Mirrors[3], d.Mirrors[0]
+	d.Mirrors[1], d.Mirrors[2] = d.Mirrors[2], d.Mirrors[1]
+
+	var b strings.Builder
+	assert.NoError(t, d.WriteGentooMirrors(&b, MakeConfOptions{Top: 2}))
+	assert.Equal(t, "GENTOO_MIRRORS=\"https://ftp.fau.de/gentoo http://ftp.fau.de/gentoo\"\n", b.String())
+
+	d.Distribution = Arch{}
+	assert.Error(t, d.WriteGentooMirrors(&b, MakeConfOptions{}))
+}
@@ -0,0 +1,77 @@
+package distributions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Evaluates a JSONPath expression on a decoded JSON value (as decoded by encoding/json in an interface{}).
+// Only a subset of JSONPath is supported: the root ("$"), child members (".name" or "['name']"),
+// array indexes ("[0]") and wildcards ("[*]" or ".*"). All matching values are returned.
+func evalJSONPath(path string, value interface{}) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", path)
+	}
+	values := []interface{}{value}
+	rest := path[1:]
+	for len(rest) != 0 {
+		var step string
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			step, rest = rest[:end], rest[end:]
+			if len(step) == 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty member name", path)
+			}
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ]", path)
+			}
+			step, rest = rest[1:end], rest[end+1:]
+			if len(step) == 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty index", path)
+			}
+			step = strings.Trim(step, "'\"")
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", path, rest[0])
+		}
+		values = jsonPathStep(step, values)
+	}
+	return values, nil
+}
+
+// Applies a single step (member name, index or wildcard) to all values
+func jsonPathStep(step string, values []interface{}) []interface{} {
+	next := []interface{}{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if step == "*" {
+				for _, child := range v {
+					next = append(next, child)
+				}
+			} else if child, ok := v[step]; ok {
+				next = append(next, child)
+			}
+		case []interface{}:
+			if step == "*" {
+				next = append(next, v...)
+			} else if index, err := strconv.Atoi(step); err == nil {
+				if index < 0 {
+					index += len(v)
+				}
+				if index >= 0 && index < len(v) {
+					next = append(next, v[index])
+				}
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,80 @@
+package distributions
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+// Metalink document, either version 3.0 (as used by MirrorManager) or
+// version 4.0 (RFC 5854, .meta4 files as used by MirrorBrain/MirrorCache)
+type metalink struct {
+	Files []metalinkFile `xml:"files>file"`
+	File  []metalinkFile `xml:"file"`
+}
+
+type metalinkFile struct {
+	Name   string        `xml:"name,attr"`
+	URLs   []metalinkURL `xml:"resources>url"`
+	URLsV4 []metalinkURL `xml:"url"`
+}
+
+type metalinkURL struct {
+	URL        string `xml:",chardata"`
+	Protocol   string `xml:"protocol,attr"`
+	Location   string `xml:"location,attr"`
+	Preference int    `xml:"preference,attr"`
+	Priority   int    `xml:"priority,attr"`
+}
+
+// Parses the mirrors of a metalink document. Each URL points to the same file on a different mirror,
+// so the suffix (path of the file, relative to the mirror) is removed from the URLs (e.g. "repodata/repomd.xml").
+func ParseMetalink(data []byte, suffix string) ([]mirrors.Mirror, error) {
+	var doc metalink
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	files := append(doc.Files, doc.File...)
+	if len(files) == 0 {
+		return nil, errors.New("no files in metalink")
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, u := range append(files[0].URLs, files[0].URLsV4...) {
+		mirror, err := metalinkMirror(strings.TrimSpace(u.URL), u.Protocol, u.Location, suffix)
+		if err != nil {
+			log.Println("Error: Failed to parse URL: ", err)
+			continue
+		}
+		mirror.Preference = u.Preference
+		mirror.Priority = u.Priority
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	return mirrorsList, nil
+}
+
+// Creates a mirror from a metalink URL and its attributes
+func metalinkMirror(link string, protocol string, location string, suffix string) (mirrors.Mirror, error) {
+	urlStr, err := url.Parse(strings.TrimSuffix(link, suffix))
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	if len(protocol) == 0 {
+		protocol = urlStr.Scheme
+	}
+	proto, err := mirrors.ToProtocol(protocol)
+	if err != nil {
+		return mirrors.Mirror{}, err
+	}
+	countryCode := strings.ToUpper(location)
+	return mirrors.Mirror{
+		Country:     utils.GetCountryName(countryCode),
+		CountryCode: countryCode,
+		URL:         urlStr,
+		Protocol:    proto,
+	}, nil
+}
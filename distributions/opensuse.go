@@ -0,0 +1,165 @@
+package distributions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/anaskhan96/soup"
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+const (
+	OPENSUSE_DOWNLOAD_URL    = "https://download.opensuse.org/"
+	OPENSUSE_REPO_FILE       = "repodata/repomd.xml"
+	OPENSUSE_DEFAULT_RELEASE = "tumbleweed"
+)
+
+// Country code in the text of a mirror list entry (e.g. "ftp.fau.de (DE)")
+var mirrorlistCountryRegexp = regexp.MustCompile(`\(([A-Za-z]{2})\b`)
+
+type OpenSUSE struct {
+	// Release ("tumbleweed" or a Leap version like "15.6")
+	Version string
+}
+
+func (suse OpenSUSE) Name() string { return "OpenSUSE" }
+
+func (suse OpenSUSE) WithRelease(release string) Distributor {
+	suse.Version = release
+	return suse
+}
+
+func (suse OpenSUSE) Release() string {
+	if len(suse.Version) == 0 {
+		return OPENSUSE_DEFAULT_RELEASE
+	}
+	return suse.Version
+}
+
+// Path of the OSS repository of the release, relative to the mirror URL
+func (suse OpenSUSE) RepoPath() string {
+	release := strings.ToLower(suse.Release())
+	if release == "tumbleweed" {
+		return "tumbleweed/repo/oss/"
+	}
+	return "distribution/leap/" + strings.TrimPrefix(release, "leap-") + "/repo/oss/"
+}
+
+// Repository metadata of the OSS repository
+func (suse OpenSUSE) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: OPENSUSE_REPO_FILE, Signature: mirrors.SIGNATURE_XML}
+}
+
+// URL of the metalink with the mirrors of the release repository
+func (suse OpenSUSE) MetalinkURL() string {
+	return OPENSUSE_DOWNLOAD_URL + suse.RepoPath() + OPENSUSE_REPO_FILE + ".meta4"
+}
+
+// URL of the HTML mirror list of the release repository
+func (suse OpenSUSE) MirrorlistURL() string {
+	return OPENSUSE_DOWNLOAD_URL + suse.RepoPath() + OPENSUSE_REPO_FILE + ".mirrorlist"
+}
+
+func (suse OpenSUSE) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return suse.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (suse OpenSUSE) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		mirrorsList, err := FetchOpenSUSEMetalinkContext(ctx, suse.MetalinkURL())
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error: Failed to fetch metalink, falling back to HTML mirror list:", err)
+			return FetchOpenSUSEMirrorlistContext(ctx, suse.MirrorlistURL())
+		}
+		return mirrorsList, err
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
+
+func FetchOpenSUSEMetalink(URL string) ([]mirrors.Mirror, error) {
+	return FetchOpenSUSEMetalinkContext(context.Background(), URL)
+}
+
+// Same as FetchOpenSUSEMetalink, but the request is aborted when the context is done.
+func FetchOpenSUSEMetalinkContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	mirrorsList, err := ParseMetalink(resp, OPENSUSE_REPO_FILE)
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+func FetchOpenSUSEMirrorlist(URL string) ([]mirrors.Mirror, error) {
+	return FetchOpenSUSEMirrorlistContext(context.Background(), URL)
+}
+
+// Same as FetchOpenSUSEMirrorlist, but the request is aborted when the context is done.
+func FetchOpenSUSEMirrorlistContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	mirrorsList, err := ParseMirrorlist(string(resp), OPENSUSE_REPO_FILE)
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Parses the mirrors of a MirrorBrain/MirrorCache ".mirrorlist" HTML page. The list items link to the
+// file on each mirror, so the suffix (path of the file, relative to the mirror) is removed from the URLs.
+// Mirrors are listed in order of preference, which is kept as their priority (1 is the highest).
+func ParseMirrorlist(page string, suffix string) ([]mirrors.Mirror, error) {
+	doc := soup.HTMLParse(page)
+	if doc.Error != nil {
+		return nil, doc.Error
+	}
+	body := doc.Find("body")
+	if body.Pointer == nil {
+		return nil, errors.New("missing body")
+	}
+	mirrorsList := []mirrors.Mirror{}
+	for _, li := range body.FindAll("li") {
+		a := li.Find("a")
+		if a.Pointer == nil {
+			continue
+		}
+		link := a.Attrs()["href"]
+		if !strings.HasSuffix(link, suffix) {
+			continue
+		}
+		location := ""
+		if match := mirrorlistCountryRegexp.FindStringSubmatch(li.FullText()); match != nil {
+			location = match[1]
+		}
+		mirror, err := metalinkMirror(link, "", location, suffix)
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		mirror.Priority = len(mirrorsList) + 1
+		mirrorsList = append(mirrorsList, mirror)
+	}
+	return mirrorsList, nil
+}
@@ -0,0 +1,53 @@
+package distributions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchOpenSUSEMetalink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/opensuse.meta4")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchOpenSUSEMetalink(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 4)
+	first := mirrorsList[0]
+	assert.Equal(t, "https://ftp.fau.de/opensuse/tumbleweed/repo/oss/", first.URL.String())
+	assert.Equal(t, mirrors.ProtoHTTPS, first.Protocol)
+	assert.Equal(t, "DE", first.CountryCode)
+	assert.Equal(t, "Germany", first.Country)
+	assert.Equal(t, 1, first.Priority)
+	assert.Equal(t, mirrors.ProtoHTTP, mirrorsList[1].Protocol)
+	assert.Equal(t, mirrors.ProtoFTP, mirrorsList[3].Protocol)
+	assert.Equal(t, 4, mirrorsList[3].Priority)
+}
+
+func TestParseMirrorlist(t *testing.T) {
+	page, err := os.ReadFile("testdata/opensuse.mirrorlist")
+	assert.NoError(t, err)
+
+	mirrorsList, err := ParseMirrorlist(string(page), OPENSUSE_REPO_FILE)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 3)
+	assert.Equal(t, "https://ftp.fau.de/opensuse/distribution/leap/15.6/repo/oss/", mirrorsList[0].URL.String())
+	assert.Equal(t, "DE", mirrorsList[0].CountryCode)
+	assert.Equal(t, 1, mirrorsList[0].Priority)
+	assert.Equal(t, mirrors.ProtoHTTP, mirrorsList[1].Protocol)
+	assert.Equal(t, "NL", mirrorsList[2].CountryCode)
+	assert.Equal(t, "Netherlands", mirrorsList[2].Country)
+	assert.Equal(t, 3, mirrorsList[2].Priority)
+}
+
+func TestOpenSUSERepoPath(t *testing.T) {
+	assert.Equal(t, "https://download.opensuse.org/tumbleweed/repo/oss/repodata/repomd.xml.meta4", OpenSUSE{}.MetalinkURL())
+	leap := OpenSUSE{}.WithRelease("15.6").(OpenSUSE)
+	assert.Equal(t, "https://download.opensuse.org/distribution/leap/15.6/repo/oss/repodata/repomd.xml.mirrorlist", leap.MirrorlistURL())
+}
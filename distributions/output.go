@@ -0,0 +1,140 @@
+package distributions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+// Returns the statistics of a mirror (empty if not measured)
+func mirrorStatistics(m *mirrors.Mirror) mirrors.MirrorStatistics {
+	if m.Statistics == nil {
+		return mirrors.MirrorStatistics{}
+	}
+	return *m.Statistics
+}
+
+// Returns up to n reachable, non-partial mirrors (in their current order) with one of the given protocols (n <= 0 means all)
+func (d *DistributionMirrors) usableMirrors(n int, schemes ...string) []*mirrors.Mirror {
+	usable := []*mirrors.Mirror{}
+	for _, mirror := range d.Mirrors {
+		if n > 0 && len(usable) == n {
+			break
+		}
+		if mirror.URL == nil || mirror.Partial || (mirror.Statistics != nil && !mirror.Statistics.Reachable()) {
+			continue
+		}
+		for _, scheme := range schemes {
+			if mirror.URL.Scheme == scheme {
+				usable = append(usable, mirror)
+				break
+			}
+		}
+	}
+	return usable
+}
+
+// Formats download speed (in bytes/s) in MB/s
+func formatSpeed(speed float64) string {
+	if speed == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f MB/s", speed/1000/1000)
+}
+
+// Formats round-trip time (unknown if not measured)
+func formatPing(rtt time.Duration) string {
+	if rtt == 0 {
+		return "-"
+	}
+	return rtt.String()
+}
+
+// Formats the status of the last probe (empty if not probed)
+func formatStatus(stats mirrors.MirrorStatistics) string {
+	if stats.Probes == 0 {
+		return ""
+	}
+	return stats.Probe.Status.String()
+}
+
+// Formats HTTP status code (empty if there was no HTTP response)
+func formatStatusCode(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", code)
+}
+
+// Formats sync lag (empty if unknown)
+func formatSyncLag(stats mirrors.MirrorStatistics) string {
+	if stats.LastUpdate.IsZero() {
+		return ""
+	}
+	return stats.SyncLag.String()
+}
+
+// Writes mirrors as a human readable list
+// TODO: Align whitespaces
+func (d *DistributionMirrors) WriteStdout(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%v %v %v %v %v %v %v\n", "Rank", "Distribution", "Country", "URL", "Avg Time", "Ping", "Speed"); err != nil {
+		return err
+	}
+	for i, distroMirror := range d.Mirrors {
+		stats := mirrorStatistics(distroMirror)
+		if _, err := fmt.Fprintf(w, "%v: %v %v %v %v %v %v\n", i, d.Distribution.Name(), distroMirror.Country, distroMirror.URL, stats.FormatTime(stats.AvgResponseTimeHTTP), formatPing(stats.ResponseTimePing), formatSpeed(stats.SpeedBps)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes mirrors in the JSON input format, along with their statistics
+func (d *DistributionMirrors) WriteJSON(w io.Writer) error {
+	distroMirrorsJson, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(distroMirrorsJson))
+	return err
+}
+
+// Writes mirrors as CSV records, along with their statistics
+func (d *DistributionMirrors) WriteCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	record := []string{"Rank", "Distribution", "Country", "URL", "Avg Time", "Ping", "DNS Lookup", "TCP Connect", "TLS Handshake", "First Byte", "Total Time", "Speed (B/s)", "Sync Lag", "Status", "Error", "HTTP Status", "Failures"}
+	if err := csvWriter.Write(record); err != nil {
+		return err
+	}
+	for i, distroMirror := range d.Mirrors {
+		stats := mirrorStatistics(distroMirror)
+		record := []string{
+			fmt.Sprintf("%v", i),
+			d.Distribution.Name(),
+			distroMirror.Country,
+			distroMirror.URL.String(),
+			stats.FormatTime(stats.AvgResponseTimeHTTP),
+			fmt.Sprintf("%v", stats.ResponseTimePing),
+			fmt.Sprintf("%v", stats.Timing.DNSLookup),
+			fmt.Sprintf("%v", stats.Timing.TCPConnect),
+			fmt.Sprintf("%v", stats.Timing.TLSHandshake),
+			fmt.Sprintf("%v", stats.Timing.FirstByte),
+			fmt.Sprintf("%v", stats.Timing.Total),
+			fmt.Sprintf("%.0f", stats.SpeedBps),
+			formatSyncLag(stats),
+			formatStatus(stats),
+			stats.Probe.Error,
+			formatStatusCode(stats.Probe.StatusCode),
+			fmt.Sprintf("%v", stats.Failures),
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,60 @@
+package distributions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Options for pacman mirrorlist output
+type PacmanOptions struct {
+	// Number of mirrors to write (default: all reachable mirrors)
+	Top int
+	// Country of the system, written in the header
+	Country string
+	// Criteria used to rank the mirrors, written in the header
+	Criteria string
+	// Generation time, written in the header (default: now)
+	Time time.Time
+}
+
+// Writes the mirrors in ranked order as a pacman mirrorlist (/etc/pacman.d/mirrorlist)
+func (d *DistributionMirrors) WritePacmanMirrorlist(w io.Writer, opts PacmanOptions) error {
+	if _, ok := d.Distribution.(Arch); !ok {
+		return fmt.Errorf("pacman mirrorlist is not supported for %v", d.Distribution.Name())
+	}
+	// pacman can not use rsync mirrors
+	best := d.usableMirrors(opts.Top, "http", "https", "ftp")
+	if len(best) == 0 {
+		return errors.New("no reachable HTTP or FTP mirror")
+	}
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+	header := []string{
+		"################################################################################",
+		"################# Arch Linux mirrorlist generated by gomirror ##################",
+		"################################################################################",
+		"",
+		fmt.Sprintf("# When:     %v", opts.Time.UTC().Format("2006-01-02 15:04:05 MST")),
+		fmt.Sprintf("# Country:  %v", opts.Country),
+		fmt.Sprintf("# Criteria: %v", opts.Criteria),
+		fmt.Sprintf("# Mirrors:  %v", len(best)),
+		"",
+	}
+	if _, err := io.WriteString(w, strings.Join(header, "\n")+"\n"); err != nil {
+		return err
+	}
+	for _, mirror := range best {
+		server := mirror.URL.String()
+		if !strings.HasSuffix(server, "/") {
+			server += "/"
+		}
+		if _, err := fmt.Fprintf(w, "Server = %v$repo/os/$arch\n", server); err != nil {
+			return err
+		}
+	}
+	return nil
+}
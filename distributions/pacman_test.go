@@ -0,0 +1,46 @@
+package distributions
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestWritePacmanMirrorlist(t *testing.T) {
+	newMirror := func(rawURL string) *mirrors.Mirror {
+		u, _ := url.Parse(rawURL)
+		return &mirrors.Mirror{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: time.Millisecond}}
+	}
+	d := &DistributionMirrors{Distribution: Arch{}, Mirrors: []*mirrors.Mirror{
+		newMirror("https://mirror.aarnet.edu.au/pub/archlinux/"),
+		newMirror("rsync://mirror.aarnet.edu.au/archlinux/"),
+		newMirror("http://ftp.cc.uoc.gr/mirrors/linux/archlinux"),
+	}}
+	expected := `################################################################################
+################# Arch Linux mirrorlist generated by gomirror ##################
+################################################################################
+
+# When:     2024-10-12 09:13:01 UTC
+# Country:  Greece
+# Criteria: mode=rank, sort=latency
+# Mirrors:  2
+
+Server = https://mirror.aarnet.edu.au/pub/archlinux/$repo/os/$arch
+Server = http://ftp.cc.uoc.gr/mirrors/linux/archlinux/$repo/os/$arch
+`
+	var b strings.Builder
+	err := d.WritePacmanMirrorlist(&b, PacmanOptions{
+		Country:  "Greece",
+		Criteria: "mode=rank, sort=latency",
+		Time:     time.Date(2024, time.October, 12, 9, 13, 1, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+
+	d.Distribution = Debian{}
+	assert.Error(t, d.WritePacmanMirrorlist(&b, PacmanOptions{}))
+}
@@ -0,0 +1,270 @@
+package distributions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+// Implemented by distributions that use dnf/yum repositories (Rocky Linux, AlmaLinux, CentOS Stream).
+// Their mirror URLs point to the release directory, which contains the repositories as "<repo>/<arch>/os/".
+type YumDistributor interface {
+	Releaser
+	MultiArch
+	// Repositories enabled by default (e.g. "BaseOS", "AppStream")
+	Repositories() []string
+	// Key used to verify the packages (file:// URL)
+	GPGKey() string
+	// Name of the repository file in /etc/yum.repos.d (without the ".repo" extension)
+	RepoFile() string
+}
+
+// Path of a repository, relative to the mirror URL
+func yumRepoPath(repo string, arch string) string {
+	return repo + "/" + arch + "/os/"
+}
+
+// Replaces the release directory of a mirror URL (e.g. "9", "9.4" or "9-stream" for release "9") with the "$releasever"
+// variable of dnf/yum, so that repository files keep working after minor updates, like the files shipped by the distributions.
+// URLs without a release directory are returned unchanged.
+func yumReleaseverURL(u *url.URL, release string) string {
+	base := u.String()
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	parent, dir := path.Split(strings.TrimSuffix(base, "/"))
+	major := majorVersion(release)
+	switch {
+	case dir == major || strings.HasPrefix(dir, major+"."):
+		return parent + "$releasever/"
+	case strings.HasPrefix(dir, major+"-"):
+		return parent + "$releasever" + strings.TrimPrefix(dir, major) + "/"
+	}
+	return base
+}
+
+// Repository metadata of the first repository of the distribution
+func yumCanary(distro YumDistributor) mirrors.Canary {
+	return mirrors.Canary{Path: yumRepoPath(distro.Repositories()[0], distro.Architecture()) + "repodata/repomd.xml", Signature: mirrors.SIGNATURE_XML}
+}
+
+// Major version of a release (e.g. "9" for "9.4")
+func majorVersion(release string) string {
+	return strings.SplitN(release, ".", 2)[0]
+}
+
+// Fetches a plain mirror list (one repository URL per line) of a dnf/yum repository
+func fetchYumMirrorlistContext(ctx context.Context, URL string, repo string, arch string) ([]mirrors.Mirror, error) {
+	// Make request
+	resp, err := utils.GetRequestContext(ctx, URL)
+	if err != nil {
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
+	}
+	mirrorsList, err := ParsePlainMirrorlist(resp, yumRepoPath(repo, arch), arch)
+	if err != nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: err}
+	}
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
+}
+
+// Parses a plain mirror list, with one repository URL per line (comments start with "#").
+// The suffix (path of the repository or of a file, relative to the mirror) is removed from the URLs,
+// after replacing the "$basearch" variable with the architecture.
+// It is an error if there are URLs, but none of them ends with the suffix.
+func ParsePlainMirrorlist(data []byte, suffix string, arch string) ([]mirrors.Mirror, error) {
+	mirrorsList := []mirrors.Mirror{}
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+		link := strings.ReplaceAll(line, "$basearch", arch)
+		// Directories may be listed without a trailing slash (files never are)
+		if (len(suffix) == 0 || strings.HasSuffix(suffix, "/")) && !strings.HasSuffix(link, "/") {
+			link += "/"
+		}
+		if !strings.HasSuffix(link, suffix) {
+			log.Println("Error: Unexpected repository URL:", line)
+			continue
+		}
+		urlStr, err := url.Parse(strings.TrimSuffix(link, suffix))
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		proto, err := mirrors.ToProtocol(urlStr.Scheme)
+		if err != nil {
+			log.Println("Error: Failed to parse URL:", err)
+			continue
+		}
+		mirrorsList = append(mirrorsList, mirrors.Mirror{URL: urlStr, Protocol: proto})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lines != 0 && len(mirrorsList) == 0 {
+		return nil, fmt.Errorf("none of the %v URLs ends with %q", lines, suffix)
+	}
+	return mirrorsList, nil
+}
+
+// Options for dnf/yum repository file output
+type RepoOptions struct {
+	// Repositories of the release (default: repositories of the distribution)
+	Repositories []string
+	// Number of mirrors to use, the best is used first and the rest as fallbacks (default: all reachable mirrors)
+	Top int
+	// Current content of the repository file. If not empty, only the baseurl, mirrorlist and metalink options
+	// of the repositories defined in it are replaced, and the rest of the file is kept (e.g. debug and source repositories)
+	Current []byte
+}
+
+// Writes the mirrors in ranked order as a dnf/yum repository file (/etc/yum.repos.d/*.repo), or updates the current one.
+// The best mirror is the first baseurl of each repository, the rest are fallbacks. The release directory of the mirrors
+// is written as "$releasever" (see yumReleaseverURL).
+func (d *DistributionMirrors) WriteRepoFile(w io.Writer, opts RepoOptions) error {
+	distro, ok := d.Distribution.(YumDistributor)
+	if !ok {
+		return fmt.Errorf("repository files are not supported for %v", d.Distribution.Name())
+	}
+	if len(opts.Repositories) == 0 {
+		opts.Repositories = distro.Repositories()
+	}
+	best := d.usableMirrors(opts.Top, "http", "https", "ftp")
+	if len(best) == 0 {
+		return errors.New("no reachable HTTP or FTP mirror")
+	}
+	// Value of the baseurl option of each repository
+	baseURLs := map[string]string{}
+	for _, repo := range opts.Repositories {
+		repoURLs := make([]string, len(best))
+		for i, mirror := range best {
+			repoURLs[i] = yumReleaseverURL(mirror.URL, distro.Release()) + yumRepoPath(repo, "$basearch")
+		}
+		baseURLs[strings.ToLower(repo)] = strings.Join(repoURLs, "\n        ")
+	}
+	if len(opts.Current) != 0 {
+		return updateRepoFile(w, opts.Current, baseURLs)
+	}
+	if _, err := fmt.Fprintf(w, "# %v %v - generated by gomirror\n", distro.Name(), distro.Release()); err != nil {
+		return err
+	}
+	for _, repo := range opts.Repositories {
+		section := fmt.Sprintf("\n[%v]\nname=%v %v - %v\nbaseurl=%v\ngpgcheck=1\nenabled=1\ngpgkey=%v\n",
+			strings.ToLower(repo),
+			distro.Name(), distro.Release(), repo,
+			baseURLs[strings.ToLower(repo)],
+			distro.GPGKey(),
+		)
+		if _, err := io.WriteString(w, section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the section name of a repository file line (e.g. "baseos" for "[baseos]"), or false if it is not a section header
+func repoSection(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(line[1 : len(line)-1])), true
+}
+
+// Reports whether the line sets a repository URL option (baseurl, mirrorlist or metalink)
+func isRepoURLOption(line string) bool {
+	key, _, found := strings.Cut(line, "=")
+	if !found {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "baseurl", "mirrorlist", "metalink":
+		return true
+	}
+	return false
+}
+
+// Returns the repositories defined in a repository file (lowercase)
+func repoSections(data []byte) map[string]bool {
+	sections := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if section, ok := repoSection(scanner.Text()); ok {
+			sections[section] = true
+		}
+	}
+	return sections
+}
+
+// Writes the current repository file, with the URL options of the given repositories replaced by their baseurl.
+// The baseurl is written in place of the first URL option of the repository (or after its header if it has none),
+// and multi-line values of the replaced options are removed.
+func updateRepoFile(w io.Writer, current []byte, baseURLs map[string]string) error {
+	// Find the repositories that already have a URL option
+	hasURL := map[string]bool{}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(current))
+	for scanner.Scan() {
+		if name, ok := repoSection(scanner.Text()); ok {
+			section = name
+		} else if isRepoURLOption(scanner.Text()) {
+			hasURL[section] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	section, replaced, continuation := "", false, false
+	scanner = bufio.NewScanner(bytes.NewReader(current))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Continuation lines of a replaced option start with whitespace
+		if continuation && len(strings.TrimSpace(line)) != 0 && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+		continuation = false
+		if name, ok := repoSection(line); ok {
+			section, replaced = name, false
+			b.WriteString(line + "\n")
+			if _, found := baseURLs[section]; found && !hasURL[section] {
+				b.WriteString("baseurl=" + baseURLs[section] + "\n")
+				replaced = true
+			}
+			continue
+		}
+		baseURL, found := baseURLs[section]
+		if found && isRepoURLOption(line) {
+			continuation = true
+			if !replaced {
+				b.WriteString("baseurl=" + baseURL + "\n")
+				replaced = true
+			}
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
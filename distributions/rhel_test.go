@@ -0,0 +1,150 @@
+package distributions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchYumMirrorlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata"+r.URL.Path)
+	}))
+	defer server.Close()
+
+	mirrorsList, err := fetchYumMirrorlistContext(context.Background(), server.URL+"/rocky.mirrorlist", "BaseOS", "x86_64")
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 3)
+	assert.Equal(t, "https://ftp.fau.de/rockylinux/10.0/", mirrorsList[0].URL.String())
+	assert.Equal(t, mirrors.ProtoHTTP, mirrorsList[1].Protocol)
+	assert.Equal(t, "https://mirrors.kernel.org/rocky/10.0/", mirrorsList[2].URL.String())
+
+	// Mirror lists with the "$basearch" variable
+	mirrorsList, err = fetchYumMirrorlistContext(context.Background(), server.URL+"/alma.mirrorlist", "BaseOS", "aarch64")
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 2)
+	assert.Equal(t, "http://ftp.cc.uoc.gr/mirrors/linux/almalinux/10/", mirrorsList[1].URL.String())
+}
+
+func TestWriteRepoFile(t *testing.T) {
+	newMirror := func(rawURL string, avg time.Duration) *mirrors.Mirror {
+		u, _ := url.Parse(rawURL)
+		return &mirrors.Mirror{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: avg}}
+	}
+	d := &DistributionMirrors{Distribution: Rocky{}.WithRelease("9"), Mirrors: []*mirrors.Mirror{
+		newMirror("https://ftp.fau.de/rockylinux/9/", time.Millisecond),
+		newMirror("rsync://ftp.fau.de/rockylinux/9/", 2*time.Millisecond),
+		newMirror("http://mirror.nl.leaseweb.net/rocky/9", 3*time.Millisecond),
+	}}
+	expected := `# Rocky 9 - generated by gomirror
+
+[baseos]
+name=Rocky 9 - BaseOS
+baseurl=https://ftp.fau.de/rockylinux/$releasever/BaseOS/$basearch/os/
+        http://mirror.nl.leaseweb.net/rocky/$releasever/BaseOS/$basearch/os/
+gpgcheck=1
+enabled=1
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-Rocky-9
+
+[appstream]
+name=Rocky 9 - AppStream
+baseurl=https://ftp.fau.de/rockylinux/$releasever/AppStream/$basearch/os/
+        http://mirror.nl.leaseweb.net/rocky/$releasever/AppStream/$basearch/os/
+gpgcheck=1
+enabled=1
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-Rocky-9
+`
+	var b strings.Builder
+	assert.NoError(t, d.WriteRepoFile(&b, RepoOptions{}))
+	assert.Equal(t, expected, b.String())
+
+	d.Distribution = CentOSStream{}
+	b.Reset()
+	assert.NoError(t, d.WriteRepoFile(&b, RepoOptions{Repositories: []string{"CRB"}, Top: 1}))
+	assert.Contains(t, b.String(), "[crb]\n")
+	assert.Contains(t, b.String(), "baseurl=https://ftp.fau.de/rockylinux/9/CRB/$basearch/os/\ngpgcheck")
+	assert.Contains(t, b.String(), "RPM-GPG-KEY-centosofficial-SHA256")
+
+	d.Distribution = Fedora{}
+	assert.Error(t, d.WriteRepoFile(&b, RepoOptions{}))
+}
+
+func TestYumReleaseverURL(t *testing.T) {
+	for _, test := range []struct{ rawURL, release, expected string }{
+		{"https://ftp.fau.de/rockylinux/9.4/", "9.4", "https://ftp.fau.de/rockylinux/$releasever/"},
+		{"https://ftp.fau.de/rockylinux/9.5", "9", "https://ftp.fau.de/rockylinux/$releasever/"},
+		{"https://mirror.stream.centos.org/10-stream/", "10", "https://mirror.stream.centos.org/$releasever-stream/"},
+		{"https://mirror.example.com/almalinux/", "10", "https://mirror.example.com/almalinux/"},
+		{"https://mirror.example.com/rocky/90/", "9", "https://mirror.example.com/rocky/90/"},
+	} {
+		u, _ := url.Parse(test.rawURL)
+		assert.Equal(t, test.expected, yumReleaseverURL(u, test.release), test.rawURL)
+	}
+}
+
+func TestWriteRepoFileUpdate(t *testing.T) {
+	u, _ := url.Parse("https://ftp.fau.de/rockylinux/9/")
+	d := &DistributionMirrors{Distribution: Rocky{}.WithRelease("9"), Mirrors: []*mirrors.Mirror{
+		{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: time.Millisecond}},
+	}}
+	current := `[baseos]
+name=Rocky Linux $releasever - BaseOS
+mirrorlist=https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo=BaseOS-$releasever$rltype
+#baseurl=http://dl.rockylinux.org/$contentdir/$releasever/BaseOS/$basearch/os/
+gpgcheck=1
+enabled=1
+
+[baseos-debuginfo]
+name=Rocky Linux $releasever - BaseOS - Debug
+mirrorlist=https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo=BaseOS-$releasever-debug$rltype
+enabled=0
+
+[appstream]
+name=Rocky Linux $releasever - AppStream
+baseurl=http://old.example.org/rocky/9/AppStream/$basearch/os/
+        http://other.example.org/rocky/9/AppStream/$basearch/os/
+enabled=1
+
+[crb]
+name=Rocky Linux $releasever - CRB
+mirrorlist=https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo=CRB-$releasever$rltype
+enabled=0
+`
+	expected := `[baseos]
+name=Rocky Linux $releasever - BaseOS
+baseurl=https://ftp.fau.de/rockylinux/$releasever/BaseOS/$basearch/os/
+#baseurl=http://dl.rockylinux.org/$contentdir/$releasever/BaseOS/$basearch/os/
+gpgcheck=1
+enabled=1
+
+[baseos-debuginfo]
+name=Rocky Linux $releasever - BaseOS - Debug
+mirrorlist=https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo=BaseOS-$releasever-debug$rltype
+enabled=0
+
+[appstream]
+name=Rocky Linux $releasever - AppStream
+baseurl=https://ftp.fau.de/rockylinux/$releasever/AppStream/$basearch/os/
+enabled=1
+
+[crb]
+name=Rocky Linux $releasever - CRB
+mirrorlist=https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo=CRB-$releasever$rltype
+enabled=0
+`
+	var b strings.Builder
+	assert.NoError(t, d.WriteRepoFile(&b, RepoOptions{Current: []byte(current)}))
+	assert.Equal(t, expected, b.String())
+
+	// Repositories without a URL option get one after their header
+	b.Reset()
+	assert.NoError(t, d.WriteRepoFile(&b, RepoOptions{Repositories: []string{"BaseOS"}, Current: []byte("[baseos]\nname=BaseOS\n")}))
+	assert.Equal(t, "[baseos]\nbaseurl=https://ftp.fau.de/rockylinux/$releasever/BaseOS/$basearch/os/\nname=BaseOS\n", b.String())
+}
@@ -0,0 +1,79 @@
+package distributions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+const (
+	ROCKY_MIRRORS_URL     = "https://mirrors.rockylinux.org/mirrorlist?arch=%v&repo=BaseOS-%v"
+	ROCKY_DEFAULT_RELEASE = "10"
+	ROCKY_DEFAULT_ARCH    = "x86_64"
+)
+
+type Rocky struct {
+	// Release version (e.g. "9", "9.4") of the repositories
+	Version string
+	// Base architecture (e.g. "x86_64", "aarch64") of the repositories
+	Arch string
+}
+
+func (rocky Rocky) Name() string { return "Rocky" }
+
+func (rocky Rocky) WithRelease(release string) Distributor {
+	rocky.Version = release
+	return rocky
+}
+
+func (rocky Rocky) Release() string {
+	if len(rocky.Version) == 0 {
+		return ROCKY_DEFAULT_RELEASE
+	}
+	return rocky.Version
+}
+
+func (rocky Rocky) WithArchitecture(arch string) Distributor {
+	rocky.Arch = arch
+	return rocky
+}
+
+func (rocky Rocky) Architecture() string {
+	if len(rocky.Arch) == 0 {
+		return ROCKY_DEFAULT_ARCH
+	}
+	return rocky.Arch
+}
+
+func (rocky Rocky) Repositories() []string { return []string{"BaseOS", "AppStream"} }
+
+func (rocky Rocky) GPGKey() string {
+	return "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-Rocky-" + majorVersion(rocky.Release())
+}
+
+func (rocky Rocky) RepoFile() string { return "rocky" }
+
+func (rocky Rocky) Canary() mirrors.Canary { return yumCanary(rocky) }
+
+// URL of the mirror list of the BaseOS repository
+func (rocky Rocky) MirrorlistURL() string {
+	return fmt.Sprintf(ROCKY_MIRRORS_URL, rocky.Architecture(), rocky.Release())
+}
+
+func (rocky Rocky) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return rocky.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (rocky Rocky) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	switch source {
+	case mirrors.SourceHTTP:
+		return fetchYumMirrorlistContext(ctx, rocky.MirrorlistURL(), "BaseOS", rocky.Architecture())
+	case mirrors.SourceJSON:
+		return mirrors.ReadMirrorsJSON(filename)
+	case mirrors.SourceTXT:
+		return mirrors.ReadMirrorsTXT(filename)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
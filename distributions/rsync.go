@@ -0,0 +1,36 @@
+package distributions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Options for rsync upstreams output
+type RsyncOptions struct {
+	// Number of mirrors to write (default: all reachable mirrors)
+	Top int
+}
+
+// Writes the rsync mirrors in ranked order, one URL per line, as upstreams to sync a local mirror from
+// (e.g. with rsync or ftpsync)
+func (d *DistributionMirrors) WriteRsyncUpstreams(w io.Writer, opts RsyncOptions) error {
+	best := d.usableMirrors(opts.Top, "rsync")
+	if len(best) == 0 {
+		return errors.New("no reachable rsync mirror")
+	}
+	if _, err := fmt.Fprintf(w, "# %v rsync upstreams - generated by gomirror\n", d.Distribution.Name()); err != nil {
+		return err
+	}
+	for _, mirror := range best {
+		upstream := mirror.URL.String()
+		if !strings.HasSuffix(upstream, "/") {
+			upstream += "/"
+		}
+		if _, err := fmt.Fprintln(w, upstream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package distributions
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestWriteRsyncUpstreams(t *testing.T) {
+	newMirror := func(rawURL string, latency time.Duration) *mirrors.Mirror {
+		u, _ := url.Parse(rawURL)
+		return &mirrors.Mirror{URL: u, Statistics: &mirrors.MirrorStatistics{AvgResponseTimeHTTP: latency}}
+	}
+	d := &DistributionMirrors{Distribution: Debian{}, Mirrors: []*mirrors.Mirror{
+		newMirror("http://ftp.gr.debian.org/debian/", time.Millisecond),
+		newMirror("rsync://ftp.gr.debian.org/debian", time.Millisecond),
+		unreachable(newMirror("rsync://ftp.de.debian.org/debian/", 0)),
+		newMirror("rsync://ftp.nl.debian.org/debian/", 2*time.Millisecond),
+	}}
+	expected := `# Debian rsync upstreams - generated by gomirror
+rsync://ftp.gr.debian.org/debian/
+rsync://ftp.nl.debian.org/debian/
+`
+	var b strings.Builder
+	err := d.WriteRsyncUpstreams(&b, RsyncOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+
+	d.Mirrors = d.Mirrors[:1]
+	assert.Error(t, d.WriteRsyncUpstreams(&b, RsyncOptions{}))
+}
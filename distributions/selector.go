@@ -0,0 +1,144 @@
+package distributions
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Simple CSS selector (e.g. "tr", "div#content", "td.url", "table.mirrors")
+type simpleSelector struct {
+	Tag     string
+	ID      string
+	Classes []string
+}
+
+// Parses a CSS selector. Only a subset of CSS is supported: type, class and ID selectors
+// and the descendant combinator (e.g. "div#content table.mirrors tr").
+func parseSelector(selector string) ([]simpleSelector, error) {
+	fields := strings.Fields(selector)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid selector %q: empty", selector)
+	}
+	selectors := make([]simpleSelector, len(fields))
+	for i, field := range fields {
+		// Split at "." and "#", keeping the separator
+		parts := []string{}
+		start := 0
+		for j := 1; j <= len(field); j++ {
+			if j == len(field) || field[j] == '.' || field[j] == '#' {
+				parts = append(parts, field[start:j])
+				start = j
+			}
+		}
+		for _, part := range parts {
+			switch {
+			case strings.HasPrefix(part, "#"):
+				selectors[i].ID = part[1:]
+			case strings.HasPrefix(part, "."):
+				selectors[i].Classes = append(selectors[i].Classes, part[1:])
+			case part == "*":
+			default:
+				selectors[i].Tag = strings.ToLower(part)
+			}
+		}
+		if len(selectors[i].ID) == 0 && len(selectors[i].Classes) == 0 && len(selectors[i].Tag) == 0 && field != "*" {
+			return nil, fmt.Errorf("invalid selector %q", selector)
+		}
+	}
+	return selectors, nil
+}
+
+func (s simpleSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if len(s.Tag) != 0 && n.Data != s.Tag {
+		return false
+	}
+	var id string
+	var classes []string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			id = attr.Val
+		case "class":
+			classes = strings.Fields(attr.Val)
+		}
+	}
+	if len(s.ID) != 0 && id != s.ID {
+		return false
+	}
+	for _, class := range s.Classes {
+		found := false
+		for _, c := range classes {
+			if c == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns all descendants of the node that match the selectors, in document order
+func selectAll(n *html.Node, selectors []simpleSelector) []*html.Node {
+	if len(selectors) == 0 {
+		return nil
+	}
+	matches := []*html.Node{}
+	seen := map[*html.Node]bool{}
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if selectors[0].matches(c) {
+				if len(selectors) == 1 {
+					if !seen[c] {
+						seen[c] = true
+						matches = append(matches, c)
+					}
+				} else {
+					for _, m := range selectAll(c, selectors[1:]) {
+						if !seen[m] {
+							seen[m] = true
+							matches = append(matches, m)
+						}
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return matches
+}
+
+// Returns the text of the node and all its descendants
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// Returns the value of an attribute of the node (empty if missing)
+func nodeAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -1,9 +1,13 @@
 package distributions
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/anaskhan96/soup"
 	"github.com/thanoskoutr/gomirror/mirrors"
@@ -11,50 +15,130 @@ import (
 )
 
 const (
-	UBUNTU_MIRRORS_URL = "https://launchpad.net/ubuntu/+archivemirrors"
+	UBUNTU_MIRRORS_URL   = "https://launchpad.net/ubuntu/+archivemirrors"
+	UBUNTU_UPSTREAM_URL  = "http://archive.ubuntu.com/ubuntu/"
+	UBUNTU_SECURITY_URL  = "http://security.ubuntu.com/ubuntu/"
+	UBUNTU_KEYRING       = "/usr/share/keyrings/ubuntu-archive-keyring.gpg"
+	UBUNTU_DEFAULT_SUITE = "noble"
+	// Mirrors reported further behind than this are outdated (see Ubuntu.MaxDelay)
+	UBUNTU_DEFAULT_MAX_DELAY = 2 * time.Hour
 )
 
-type Ubuntu struct{}
+// Delay of the freshness statuses of Launchpad
+var ubuntuStatusDelays = map[string]time.Duration{
+	"up to date":       0,
+	"one hour behind":  time.Hour,
+	"two hours behind": 2 * time.Hour,
+	"six hours behind": 6 * time.Hour,
+	"one day behind":   24 * time.Hour,
+	"two days behind":  2 * 24 * time.Hour,
+	"one week behind":  7 * 24 * time.Hour,
+}
+
+type Ubuntu struct {
+	// Suite (codename) used for release specific operations
+	Suite string
+	// Include mirrors reported as outdated (or with unknown freshness) by Launchpad
+	IncludeOutdated bool
+	// Mirrors reported further behind than this are outdated (default: UBUNTU_DEFAULT_MAX_DELAY)
+	MaxDelay time.Duration
+}
 
 func (ub Ubuntu) Name() string { return "Ubuntu" }
 
-func (ub Ubuntu) GetMirrors(source mirrors.MirrorSource, filename string) []mirrors.Mirror {
+func (ub Ubuntu) WithRelease(release string) Distributor {
+	ub.Suite = release
+	return ub
+}
+
+func (ub Ubuntu) Release() string {
+	if len(ub.Suite) == 0 {
+		return UBUNTU_DEFAULT_SUITE
+	}
+	return ub.Suite
+}
+
+func (ub Ubuntu) UpstreamURL() string { return UBUNTU_UPSTREAM_URL }
+
+func (ub Ubuntu) Components() []string {
+	return []string{"main", "restricted", "universe", "multiverse"}
+}
+
+func (ub Ubuntu) Keyring() string { return UBUNTU_KEYRING }
+
+func (ub Ubuntu) SecuritySource(suite string) (string, string) {
+	return UBUNTU_SECURITY_URL, suite + "-security"
+}
+
+// Compressed listing of the whole archive (several MB)
+func (ub Ubuntu) SpeedTestPath() string { return "ls-lR.gz" }
+
+// Signed Release file of the suite (its date is the freshness of the mirror)
+func (ub Ubuntu) Canary() mirrors.Canary {
+	return mirrors.Canary{Path: InReleasePath(ub.Release()), Signature: mirrors.SIGNATURE_PGP_SIGNED, ReleaseDate: true}
+}
+
+func (ub Ubuntu) GetMirrors(source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
+	return ub.GetMirrorsContext(context.Background(), source, filename)
+}
+
+func (ub Ubuntu) GetMirrorsContext(ctx context.Context, source mirrors.MirrorSource, filename string) ([]mirrors.Mirror, error) {
 	switch source {
 	case mirrors.SourceHTTP:
-		// TODO: If error online fallback to internal mirrors
-		return FetchUbuntuMirrors(UBUNTU_MIRRORS_URL)
+		return FetchUbuntuMirrorsContext(ctx, UBUNTU_MIRRORS_URL)
 	case mirrors.SourceJSON:
 		return mirrors.ReadMirrorsJSON(filename)
 	case mirrors.SourceTXT:
 		return mirrors.ReadMirrorsTXT(filename)
 	default:
-		return []mirrors.Mirror{}
+		return nil, fmt.Errorf("unsupported source type: %v", source)
+	}
+}
+
+// Keeps the mirrors that are up to date, according to the freshness status of Launchpad.
+// Mirrors with unknown statuses are outdated, and mirrors without status (e.g. from a plain list) are kept.
+func (ub Ubuntu) KeepMirror(mirror mirrors.Mirror) bool {
+	if ub.IncludeOutdated || len(mirror.Status) == 0 {
+		return true
 	}
+	maxDelay := ub.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = UBUNTU_DEFAULT_MAX_DELAY
+	}
+	delay, known := ubuntuStatusDelays[strings.ToLower(mirror.Status)]
+	return known && delay <= maxDelay
+}
+
+func FetchUbuntuMirrors(URL string) ([]mirrors.Mirror, error) {
+	return FetchUbuntuMirrorsContext(context.Background(), URL)
 }
 
-// TODO: Error handling
-func FetchUbuntuMirrors(URL string) []mirrors.Mirror {
+// Same as FetchUbuntuMirrors, but the request is aborted when the context is done.
+func FetchUbuntuMirrorsContext(ctx context.Context, URL string) ([]mirrors.Mirror, error) {
 	// Definitions
 	countries := []string{}
 	countryCnt := 0
 	mirrorsList := []mirrors.Mirror{}
 
 	// Make request
-	resp, err := soup.Get(URL)
+	resp, err := utils.GetRequestContext(ctx, URL)
 	if err != nil {
-		log.Fatal("Can not parse Ubuntu mirrors list: ", err)
+		return nil, &mirrors.FetchError{Source: URL, Err: err}
 	}
 	// Parse HTML mirror list
-	doc := soup.HTMLParse(resp)
+	doc := soup.HTMLParse(string(resp))
 	maincontent := doc.Find("div", "id", "maincontent")
+	if maincontent.Pointer == nil {
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing main content section")}
+	}
 	table := maincontent.Find("table", "id", "mirrors_list")
 	if table.Pointer == nil {
-		log.Fatal("Can not parse Ubuntu mirrors list")
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing mirrors table")}
 	}
 	// Parse table rows
 	tbody := table.Find("tbody")
 	if tbody.Pointer == nil {
-		log.Fatal("Can not parse Ubuntu mirrors list")
+		return nil, &mirrors.ParseError{Source: URL, Err: errors.New("missing mirrors table body")}
 	}
 	trs := tbody.FindAll("tr")
 	for _, tr := range trs {
@@ -74,6 +158,16 @@ func FetchUbuntuMirrors(URL string) []mirrors.Mirror {
 		if len(tds) < 2 {
 			continue
 		}
+		if countryCnt == 0 {
+			return nil, &mirrors.ParseError{Source: URL, Err: errors.New("mirror listed before any country")}
+		}
+		// Find bandwidth and freshness status (e.g. "1 Gbps", "Up to date")
+		bandwidth, status := "", ""
+		if len(tds) >= 4 {
+			bandwidth = strings.TrimSpace(tds[2].FullText())
+			status = strings.Join(strings.Fields(tds[3].FullText()), " ")
+		}
+		delay := ubuntuStatusDelays[strings.ToLower(status)]
 		aTags := tds[1].FindAll("a")
 		for _, a := range aTags {
 			// Find mirror link
@@ -89,8 +183,14 @@ func FetchUbuntuMirrors(URL string) []mirrors.Mirror {
 				Country:     countries[countryCnt-1],
 				CountryCode: utils.GetCountryCode(countries[countryCnt-1]),
 				URL:         urlStr,
+				Bandwidth:   bandwidth,
+				Status:      status,
+				Delay:       delay,
 			})
 		}
 	}
-	return mirrorsList
+	if len(mirrorsList) == 0 {
+		return nil, mirrors.ErrNoMirrors
+	}
+	return mirrorsList, nil
 }
@@ -1,3 +0,0 @@
-package distributions
-
-var UbuntuBackupMirrors map[string]interface{}
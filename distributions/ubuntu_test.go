@@ -0,0 +1,78 @@
+package distributions
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thanoskoutr/gomirror/mirrors"
+)
+
+func TestFetchUbuntuMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/ubuntu.html")
+	}))
+	defer server.Close()
+
+	mirrorsList, err := FetchUbuntuMirrors(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, mirrorsList, 5)
+
+	first := mirrorsList[0]
+	assert.Equal(t, "http://ftp.cc.uoc.gr/mirrors/linux/ubuntu/packages/", first.URL.String())
+	assert.Equal(t, "Greece", first.Country)
+	assert.Equal(t, "10 Gbps", first.Bandwidth)
+	assert.Equal(t, "Up to date", first.Status)
+	assert.Equal(t, time.Duration(0), first.Delay)
+	assert.False(t, first.Active)
+	assert.Equal(t, "rsync://ftp.cc.uoc.gr/ubuntu/", mirrorsList[1].URL.String())
+	assert.Equal(t, "10 Gbps", mirrorsList[1].Bandwidth)
+
+	assert.Equal(t, "One day behind", mirrorsList[2].Status)
+	assert.Equal(t, 24*time.Hour, mirrorsList[2].Delay)
+	assert.Equal(t, "Germany", mirrorsList[3].Country)
+	assert.Equal(t, 2*time.Hour, mirrorsList[3].Delay)
+	assert.Equal(t, "Last update unknown", mirrorsList[4].Status)
+	assert.Equal(t, time.Duration(0), mirrorsList[4].Delay)
+
+}
+
+func TestUbuntuKeepMirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/ubuntu.html")
+	}))
+	defer server.Close()
+	mirrorsList, err := FetchUbuntuMirrors(server.URL)
+	assert.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "ubuntu.json")
+	d := &DistributionMirrors{Distribution: Ubuntu{}}
+	for i := range mirrorsList {
+		d.Mirrors = append(d.Mirrors, &mirrorsList[i])
+	}
+	var b bytes.Buffer
+	assert.NoError(t, d.WriteJSON(&b))
+	assert.NoError(t, os.WriteFile(filename, b.Bytes(), 0644))
+
+	// Outdated mirrors are excluded whatever the source
+	d = &DistributionMirrors{Distribution: Ubuntu{}}
+	assert.NoError(t, d.UpdateMirrors(mirrors.SourceJSON, filename))
+	assert.Len(t, d.Mirrors, 3)
+	assert.Equal(t, "https://ftp.fau.de/ubuntu/", d.Mirrors[2].URL.String())
+
+	// Maximum delay
+	d.Distribution = Ubuntu{MaxDelay: 24 * time.Hour}
+	assert.NoError(t, d.UpdateMirrors(mirrors.SourceJSON, filename))
+	assert.Len(t, d.Mirrors, 4)
+	d.Distribution = Ubuntu{IncludeOutdated: true}
+	assert.NoError(t, d.UpdateMirrors(mirrors.SourceJSON, filename))
+	assert.Len(t, d.Mirrors, 5)
+
+	// Mirrors without status are kept
+	assert.True(t, Ubuntu{}.KeepMirror(mirrors.Mirror{}))
+	assert.False(t, Ubuntu{}.KeepMirror(mirrors.Mirror{Status: "Last update unknown"}))
+}
@@ -3,22 +3,25 @@ module github.com/thanoskoutr/gomirror
 go 1.19
 
 require (
-	github.com/anaskhan96/soup v1.2.5 // indirect
+	github.com/anaskhan96/soup v1.2.5
+	github.com/go-ping/ping v1.1.0
+	github.com/pariz/gountries v0.1.6
+	github.com/schollz/progressbar/v3 v3.11.0
+	github.com/stretchr/testify v1.8.0
+	golang.org/x/net v0.0.0-20220930213112-107f3e3c3b0b
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/go-ping/ping v1.1.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
-	github.com/pariz/gountries v0.1.6 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.2 // indirect
-	github.com/schollz/progressbar/v3 v3.11.0 // indirect
-	github.com/stretchr/testify v1.8.0 // indirect
-	golang.org/x/net v0.0.0-20220930213112-107f3e3c3b0b // indirect
 	golang.org/x/sync v0.0.0-20220929204114-8fcdb60fdcc0 // indirect
 	golang.org/x/sys v0.0.0-20221010170243-090e33056c14 // indirect
 	golang.org/x/term v0.0.0-20220919170432-7a66f970e087 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
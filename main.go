@@ -1,12 +1,18 @@
 package main
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/thanoskoutr/gomirror/distributions"
 	"github.com/thanoskoutr/gomirror/mirrors"
@@ -32,12 +38,40 @@ func main() {
 	// TODO: Add flag for writing to file
 	// Supported Flags
 	var (
-		distro       = flag.String("distro", "", "The distribution to rank mirrors. Supported: \"Ubuntu\", \"Debian\", \"Arch\"")
-		mode         = flag.String("mode", "rank", "Mode of operation. Supported: \"rank\": Rank all mirrors based on your location, \"best\": Find best mirror based on your location")
-		sourceType   = flag.String("source", "http", "The type of source for mirror list. Supported: \"http\", \"json\", \"txt\"")
+		distro       = flag.String("distro", "", "The distribution to rank mirrors. Supported: \"Ubuntu\", \"Debian\", \"Arch\", \"Fedora\", \"OpenSUSE\", \"Alpine\", \"Gentoo\", \"Rocky\", \"AlmaLinux\", \"CentOSStream\", \"Custom\" (only with \"json\", \"txt\" sources)")
+		definition   = flag.String("definition", "", "A YAML or JSON file that defines a custom distribution (mirror list URL, parser, probe path and output template). Replaces -distro")
+		mode         = flag.String("mode", "rank", "Mode of operation. Supported: \"rank\": Rank all mirrors based on your location, \"best\": Find best mirror based on your location, \"apply\": Write ranked mirrors to the package manager configuration (with backup), \"restore\": Restore the package manager configuration from the latest backup")
+		root         = flag.String("root", "/", "The root directory of the system for \"apply\" and \"restore\" modes (e.g. a chroot)")
+		sourceType   = flag.String("source", "http", "The type of source for mirror list. Supported: \"http\", \"json\", \"txt\", \"backup\" (snapshot embedded in gomirror)")
 		sourceFile   = flag.String("file", "", "The file with the mirrors. Valid only for \"json\", \"txt\" source")
+		offline      = flag.Bool("offline", false, "Use the snapshot of the mirror list embedded in gomirror, instead of fetching it (same as -source backup)")
+		noCache      = flag.Bool("no-cache", false, "Always fetch the mirror list, without using or updating the cache")
+		refresh      = flag.Bool("refresh", false, "Revalidate the cached mirror list, even if it is younger than -cache-ttl")
+		clearCache   = flag.Bool("clear-cache", false, "Remove all cached mirror lists before fetching")
+		cacheTTL     = flag.Duration("cache-ttl", utils.CACHE_DEFAULT_TTL, "Time that the cached mirror list is used without revalidation. The cache is stored under $XDG_CACHE_HOME/gomirror")
+		release      = flag.String("release", "", "The release of the distribution (e.g. suite for \"Ubuntu\", \"Debian\", version for \"Fedora\", \"Rocky\", \"AlmaLinux\", \"CentOSStream\", \"tumbleweed\" or Leap version for \"OpenSUSE\", branch for \"Alpine\"). Defaults to the latest stable release")
+		architecture = flag.String("arch", "", "The architecture of the distribution (e.g. \"aarch64\"). Supported for: \"Fedora\", \"Rocky\", \"AlmaLinux\", \"CentOSStream\". Defaults to \"x86_64\"")
 		countryInput = flag.String("country", "", "The country where the system is located")
-		output       = flag.String("output", "stdout", "The output format for the results. Supported: \"stdout\", \"json\", \"txt\", \"csv\"")
+		output       = flag.String("output", "stdout", "The output format for the results. Supported: \"stdout\", \"json\", \"txt\", \"csv\", \"sources.list\", \"deb822\", \"pacman\", \"apk\", \"repo\", \"make.conf\", \"rsync\" (best rsync upstreams), \"template\" (output template of the definition)")
+		top          = flag.Int("top", 0, "The number of best mirrors written in package manager outputs. Defaults to 1 for APT, apk and make.conf outputs, all reachable mirrors for others")
+		suites       = flag.String("suites", "", "Comma separated suites for APT outputs. Defaults to the release and its updates suite")
+		components   = flag.String("components", "", "Comma separated components for APT outputs (repositories for apk and repo outputs). Defaults to the components of the distribution")
+		debSrc       = flag.Bool("deb-src", false, "Include source packages (deb-src) in APT outputs")
+		workers      = flag.Int("workers", 0, "Maximum number of mirrors measured in parallel. 0 means no limit")
+		rateLimit    = flag.Float64("rate", 0, "Maximum number of requests started per second. 0 means no limit")
+		perHost      = flag.Int("per-host", 0, "Maximum number of parallel requests to the same host. 0 means no limit")
+		speedTest    = flag.Bool("speed", false, "Measure download speed of mirrors, by downloading a known file of the distribution")
+		speedPath    = flag.String("speed-path", "", "The file used to measure download speed, relative to the mirror URL. Defaults to a known file of the distribution")
+		speedBytes   = flag.Int64("speed-bytes", 10*1000*1000, "Maximum bytes downloaded from each mirror when measuring download speed")
+		speedTime    = flag.Duration("speed-time", 5*time.Second, "Maximum time spent downloading from each mirror when measuring download speed")
+		speedTop     = flag.Int("speed-top", 10, "The number of mirrors with the lowest response time that are speed tested (one at a time). 0 means all reachable mirrors")
+		freshness    = flag.Bool("freshness", false, "Check how far behind the upstream archive each mirror is. Supported for: \"Ubuntu\", \"Debian\"")
+		maxLag       = flag.Duration("max-lag", 0, "Exclude mirrors that are further behind than this (e.g. \"6h\"). Implies -freshness. 0 means no limit")
+		sortBy       = flag.String("sort", "latency", "The criteria for ranking mirrors. Supported: \"latency\", \"speed\", \"score\" (mirror status score blended with latency)")
+		scoreWeight  = flag.Float64("score-weight", 0.5, "The share (0-1) of the mirror status score when sorting by \"score\", the rest is latency")
+		inactive     = flag.Bool("include-inactive", false, "Include mirrors reported as inactive, outdated or not fully synced. Supported for: \"Arch\", \"Ubuntu\"")
+		maxDelay     = flag.Duration("max-delay", 0, "Exclude mirrors that the mirror list reports further behind than this. Supported for: \"Ubuntu\". 0 means the default of the distribution (\"Ubuntu\": 2h)")
+		deadline     = flag.Duration("deadline", 0, "Overall time limit for fetching and ranking mirrors (e.g. \"20s\"). Mirrors not measured in time are ranked last. 0 means no limit")
 	)
 	// Parse Flags
 	flag.Parse()
@@ -45,16 +79,106 @@ func main() {
 	fmt.Fprintf(os.Stderr, "----------------------\n")
 
 	// Validate Distribution
-	if len(*distro) == 0 {
-		fmt.Fprintf(os.Stderr, "Select a distribution to start\n")
+	var err error
+	if len(*definition) != 0 {
+		def, err := distributions.LoadDefinition(*definition)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		distroMirrors.Distribution = distributions.CustomDistributor{Definition: def}
+		fmt.Fprintf(os.Stderr, "Definition: %v (%v)\n", *definition, def.Name)
+	} else {
+		if len(*distro) == 0 {
+			fmt.Fprintf(os.Stderr, "Select a distribution to start\n")
+			os.Exit(1)
+		}
+		distroMirrors.Distribution, err = distributions.ToDistribution(*distro)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Validate Release
+	if releaser, ok := distroMirrors.Distribution.(distributions.Releaser); ok {
+		distroMirrors.Distribution = releaser.WithRelease(*release)
+		fmt.Fprintf(os.Stderr, "Release: %v\n", distroMirrors.Distribution.(distributions.Releaser).Release())
+	} else if len(*release) != 0 {
+		fmt.Fprintf(os.Stderr, "Releases are not supported for %v\n", distroMirrors.Distribution.Name())
 		os.Exit(1)
 	}
-	var err error
-	distroMirrors.Distribution, err = distributions.ToDistribution(*distro)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+
+	// Validate Architecture
+	if multiArch, ok := distroMirrors.Distribution.(distributions.MultiArch); ok {
+		distroMirrors.Distribution = multiArch.WithArchitecture(*architecture)
+		fmt.Fprintf(os.Stderr, "Architecture: %v\n", distroMirrors.Distribution.(distributions.MultiArch).Architecture())
+	} else if len(*architecture) != 0 {
+		fmt.Fprintf(os.Stderr, "Architectures are not supported for %v\n", distroMirrors.Distribution.Name())
+		os.Exit(1)
+	}
+
+	// Validate Inactive Mirrors
+	if *inactive {
+		switch distro := distroMirrors.Distribution.(type) {
+		case distributions.Arch:
+			distro.IncludeInactive = true
+			distroMirrors.Distribution = distro
+		case distributions.Ubuntu:
+			distro.IncludeOutdated = true
+			distroMirrors.Distribution = distro
+		default:
+			fmt.Fprintf(os.Stderr, "Including inactive mirrors is not supported for %v\n", distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Include Inactive Mirrors: %v\n", *inactive)
+	}
+
+	// Validate Maximum Reported Delay
+	if *maxDelay < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid maximum delay: %v\n", *maxDelay)
 		os.Exit(1)
 	}
+	if *maxDelay > 0 {
+		switch distro := distroMirrors.Distribution.(type) {
+		case distributions.Ubuntu:
+			distro.MaxDelay = *maxDelay
+			distroMirrors.Distribution = distro
+		default:
+			fmt.Fprintf(os.Stderr, "Maximum delay is not supported for %v\n", distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Maximum Delay: %v\n", *maxDelay)
+	}
+
+	// Restore package manager configuration (no mirrors needed)
+	if *mode == "restore" {
+		configFiles, err := distributions.FindConfigFiles(distroMirrors.Distribution, *root, splitList(*components))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		// Restore as many files as possible, so that the system is not left half-restored
+		failed := 0
+		for _, configFile := range configFiles {
+			backup, err := utils.RestoreFile(configFile.Path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can not restore %v: %v\n", configFile.Path, err)
+				failed++
+				continue
+			}
+			if utils.IsCreated(backup) {
+				fmt.Fprintf(os.Stderr, "Removed %v (created by apply)\n", configFile.Path)
+			} else {
+				fmt.Fprintf(os.Stderr, "Restored %v from %v\n", configFile.Path, backup)
+			}
+		}
+		if failed != 0 {
+			fmt.Fprintf(os.Stderr, "Failed to restore %v of %v files\n", failed, len(configFiles))
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Validate Country
 	if len(*countryInput) == 0 {
@@ -72,25 +196,70 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Unsupported source type: %v\n", *sourceType)
 		os.Exit(1)
 	}
+	if *offline {
+		if mirrorSourceType != mirrors.SourceHTTP && mirrorSourceType != mirrors.SourceBackup {
+			fmt.Fprintf(os.Stderr, "Offline mode can not be used with source type: %v\n", mirrorSourceType)
+			os.Exit(1)
+		}
+		mirrorSourceType = mirrors.SourceBackup
+	}
 	fmt.Fprintf(os.Stderr, "Mirror Source Type: %v\n", mirrorSourceType)
 
 	// Validate Mirrors Source file
 	mirrorSourceFile = *sourceFile
-	if len(mirrorSourceFile) != 0 && mirrorSourceType == mirrors.SourceHTTP {
+	if len(mirrorSourceFile) != 0 && (mirrorSourceType == mirrors.SourceHTTP || mirrorSourceType == mirrors.SourceBackup) {
 		fmt.Fprintf(os.Stderr, "No valid source type specified for source file: %v\n", mirrorSourceFile)
 		os.Exit(1)
 	}
-	if len(mirrorSourceFile) == 0 && mirrorSourceType != mirrors.SourceHTTP {
+	if len(mirrorSourceFile) == 0 && (mirrorSourceType == mirrors.SourceJSON || mirrorSourceType == mirrors.SourceTXT) {
 		fmt.Fprintf(os.Stderr, "No Mirror file specified\n")
 		os.Exit(1)
 	}
 	fmt.Fprintf(os.Stderr, "Mirror Source File: %v\n", mirrorSourceFile)
 
+	// Validate Cache
+	if *clearCache {
+		cacheDir, err := utils.DefaultCacheDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can not clear cache: %v\n", err)
+			os.Exit(1)
+		}
+		if err := (&utils.Cache{Dir: cacheDir}).Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Can not clear cache %v: %v\n", cacheDir, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Cleared cache: %v\n", cacheDir)
+	}
+	var cache *utils.Cache
+	if !*noCache && mirrorSourceType == mirrors.SourceHTTP {
+		if *cacheTTL < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid cache TTL: %v\n", *cacheTTL)
+			os.Exit(1)
+		}
+		cacheDir, err := utils.DefaultCacheDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cache disabled: %v\n", err)
+		} else {
+			cache = &utils.Cache{Dir: cacheDir, TTL: *cacheTTL, Refresh: *refresh}
+			fmt.Fprintf(os.Stderr, "Cache: %v (TTL: %v, refresh: %v)\n", cacheDir, *cacheTTL, *refresh)
+		}
+	}
+
 	// Validate Mode
+	var configFiles []distributions.ConfigFile
 	switch *mode {
 	case "rank":
 	case "best":
 		fmt.Fprintf(os.Stderr, "Operation Mode: %v\n", *mode)
+	case "apply":
+		configFiles, err = distributions.FindConfigFiles(distroMirrors.Distribution, *root, splitList(*components))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		for _, configFile := range configFiles {
+			fmt.Fprintf(os.Stderr, "Operation Mode: %v (%v as %v)\n", *mode, configFile.Path, configFile.Format)
+		}
 
 	default:
 		fmt.Fprintf(os.Stderr, "Unsupported mode: %v\n", *mode)
@@ -104,13 +273,153 @@ func main() {
 	case "csv":
 		fmt.Fprintf(os.Stderr, "Output format: %v\n", *mode)
 	case "txt":
+	case "sources.list", "deb822":
+		if _, ok := distroMirrors.Distribution.(distributions.AptDistributor); !ok {
+			fmt.Fprintf(os.Stderr, "Output format %v is not supported for %v\n", *output, distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "pacman":
+		if _, ok := distroMirrors.Distribution.(distributions.Arch); !ok {
+			fmt.Fprintf(os.Stderr, "Output format %v is not supported for %v\n", *output, distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "apk":
+		if _, ok := distroMirrors.Distribution.(distributions.Alpine); !ok {
+			fmt.Fprintf(os.Stderr, "Output format %v is not supported for %v\n", *output, distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "repo":
+		if _, ok := distroMirrors.Distribution.(distributions.YumDistributor); !ok {
+			fmt.Fprintf(os.Stderr, "Output format %v is not supported for %v\n", *output, distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "make.conf":
+		if _, ok := distroMirrors.Distribution.(distributions.Gentoo); !ok {
+			fmt.Fprintf(os.Stderr, "Output format %v is not supported for %v\n", *output, distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "rsync":
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
+	case "template":
+		if custom, ok := distroMirrors.Distribution.(distributions.CustomDistributor); !ok || custom.Definition == nil || len(custom.Definition.Output) == 0 {
+			fmt.Fprintf(os.Stderr, "Output format %v requires a definition with an output template\n", *output)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Output format: %v\n", *output)
 	default:
 		fmt.Fprintf(os.Stderr, "Unsupported output format: %v\n", *output)
 		os.Exit(1)
 	}
+	if *top < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid number of mirrors: %v\n", *top)
+		os.Exit(1)
+	}
+	aptOptions := distributions.AptOptions{
+		Suites:     splitList(*suites),
+		Components: splitList(*components),
+		Source:     *debSrc,
+		Top:        *top,
+	}
+	repoOptions := distributions.RepoOptions{
+		Repositories: splitList(*components),
+		Top:          *top,
+	}
+
+	// Validate Speed Test
+	if *speedTest {
+		if len(*speedPath) == 0 {
+			speedTester, ok := distroMirrors.Distribution.(distributions.SpeedTester)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "No known file for measuring download speed of %v, select one with -speed-path\n", distroMirrors.Distribution.Name())
+				os.Exit(1)
+			}
+			*speedPath = speedTester.SpeedTestPath()
+		}
+		if *speedBytes <= 0 || *speedTime <= 0 || *speedTop < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid speed test limits: bytes: %v, time: %v, top: %v\n", *speedBytes, *speedTime, *speedTop)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Speed Test: %v (up to %v bytes, %v, top %v mirrors)\n", *speedPath, *speedBytes, *speedTime, *speedTop)
+	} else {
+		*speedPath = ""
+	}
+
+	// Validate Probe
+	var canary mirrors.Canary
+	if validator, ok := distroMirrors.Distribution.(distributions.Validator); ok {
+		canary = validator.Canary()
+	}
+	if prober, ok := distroMirrors.Distribution.(distributions.Prober); ok && len(prober.ProbePath()) != 0 {
+		canary = mirrors.Canary{Path: prober.ProbePath()}
+	}
+	if len(canary.Path) != 0 {
+		fmt.Fprintf(os.Stderr, "Probe: %v\n", canary.Path)
+	}
+
+	// Validate Freshness Check
+	var releasePath, upstreamURL string
+	if *maxLag < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid maximum lag: %v\n", *maxLag)
+		os.Exit(1)
+	}
+	if *freshness || *maxLag > 0 {
+		aptDistro, ok := distroMirrors.Distribution.(distributions.AptDistributor)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Freshness check is not supported for %v\n", distroMirrors.Distribution.Name())
+			os.Exit(1)
+		}
+		releasePath = distributions.ReleasePath(aptDistro.Release())
+		upstreamURL = aptDistro.UpstreamURL()
+		fmt.Fprintf(os.Stderr, "Freshness Check: %v (upstream: %v)\n", releasePath, upstreamURL)
+		if *maxLag > 0 {
+			fmt.Fprintf(os.Stderr, "Maximum Lag: %v\n", *maxLag)
+		}
+	}
+
+	// Validate Sort Criteria
+	sortKey, err := distributions.ToSortKey(*sortBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if sortKey == distributions.SortSpeed && !*speedTest {
+		fmt.Fprintf(os.Stderr, "Sorting by speed requires -speed\n")
+		os.Exit(1)
+	}
+	if *scoreWeight < 0 || *scoreWeight > 1 {
+		fmt.Fprintf(os.Stderr, "Invalid score weight: %v\n", *scoreWeight)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Sort by: %v\n", sortKey)
+
+	if *workers < 0 || *rateLimit < 0 || *perHost < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid request limits: workers: %v, rate: %v, per-host: %v\n", *workers, *rateLimit, *perHost)
+		os.Exit(1)
+	}
+	if *deadline < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid deadline: %v\n", *deadline)
+		os.Exit(1)
+	}
+	if *deadline > 0 {
+		fmt.Fprintf(os.Stderr, "Deadline: %v\n", *deadline)
+	}
 	fmt.Fprintf(os.Stderr, "----------------------\n")
 
 	// ------------------------------------ OPERATIONS
+	// Stop on interrupt or when the deadline is reached
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+
 	// Add Mirrors manually (for testing)
 	distroMirrors.Mirrors = []*mirrors.Mirror{
 		{
@@ -127,7 +436,14 @@ func main() {
 		},
 	}
 	// Update Mirrors
-	distroMirrors.UpdateMirrors(mirrorSourceType, mirrorSourceFile)
+	fetchCtx := ctx
+	if cache != nil {
+		fetchCtx = utils.WithCache(ctx, cache)
+	}
+	if err := distroMirrors.UpdateMirrorsContext(fetchCtx, mirrorSourceType, mirrorSourceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
 	// DEBUG: Print Mirrors
 	// fmt.Printf("Mirrors: %v\n", distroMirrors)
@@ -137,51 +453,135 @@ func main() {
 	// fmt.Println(string(distroMirrorsJson))
 
 	// Update Statistics
-	distroMirrors.UpdateMirrorStatistics(STATISTICS_ROUNDS)
+	statisticsOptions := distributions.StatisticsOptions{
+		Rounds:    STATISTICS_ROUNDS,
+		Workers:   *workers,
+		RateLimit: *rateLimit,
+		PerHost:   *perHost,
+		Canary:    canary,
+
+		SpeedPath:     *speedPath,
+		SpeedBytes:    *speedBytes,
+		SpeedDuration: *speedTime,
+		SpeedTop:      *speedTop,
+
+		ReleasePath: releasePath,
+		UpstreamURL: upstreamURL,
+	}
+	if err := distroMirrors.UpdateMirrorStatisticsContext(ctx, statisticsOptions); err != nil {
+		fmt.Fprintf(os.Stderr, "Stopped early (%v), showing partial results\n", err)
+	}
 	// DEBUG: Print Mirrors
 	// fmt.Printf("Mirrors (Updated): %v\n", distroMirrors)
 
+	// Exclude mirrors that are too far behind
+	if *maxLag > 0 {
+		distroMirrors.FilterMirrors(distributions.MaxSyncLag(*maxLag))
+		if len(distroMirrors.Mirrors) == 0 {
+			fmt.Fprintf(os.Stderr, "No mirrors are within the maximum lag of %v\n", *maxLag)
+			os.Exit(1)
+		}
+	}
+
 	// Operate based on selected mode
+	if sortKey == distributions.SortScore {
+		distroMirrors.UpdateRankScores(*scoreWeight)
+	}
 	switch *mode {
-	case "rank":
+	case "rank", "apply":
 		// Sort Mirrors
-		distroMirrors.SortMirrors()
+		distroMirrors.SortMirrorsBy(sortKey)
 		fmt.Fprintf(os.Stderr, "Ranked Mirrors:\n")
 	case "best":
-		// Print best Mirror (based on HTTP response)
-		bestMirror := distroMirrors.BestMirror()
+		// Print best Mirror (based on sort criteria)
+		bestMirror, err := distroMirrors.BestMirrorBy(sortKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can not find best mirror: %v\n", err)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "Best Mirror (relative):\n")
 		distroMirrors.Mirrors = []*mirrors.Mirror{&bestMirror}
 	}
 
 	// Output results
-	// TODO: Make them methods of Distribution Mirrors
-	// TODO: Create common output for all formats
-	switch *output {
-	case "stdout":
-		// TODO: Align whitespaces
-		fmt.Printf("%v %v %v %v %v\n", "Rank", "Distribution", "Country", "URL", "Avg Time")
-		for i, distroMirror := range distroMirrors.Mirrors {
-			fmt.Printf("%v: %v %v %v %v\n", i, distroMirrors.Distribution.Name(), distroMirror.Country, distroMirror.URL, distroMirror.Statistics.AvgResponseTimeHTTP)
+	writeOutput := func(w io.Writer, format string) error {
+		switch format {
+		case "stdout":
+			return distroMirrors.WriteStdout(w)
+		case "json":
+			return distroMirrors.WriteJSON(w)
+		case "csv":
+			return distroMirrors.WriteCSV(w)
+		case "sources.list":
+			return distroMirrors.WriteSourcesList(w, aptOptions)
+		case "deb822":
+			return distroMirrors.WriteDeb822(w, aptOptions)
+		case "pacman":
+			return distroMirrors.WritePacmanMirrorlist(w, distributions.PacmanOptions{
+				Top:      *top,
+				Country:  country,
+				Criteria: fmt.Sprintf("mode=%v, sort=%v", *mode, sortKey),
+			})
+		case "apk":
+			return distroMirrors.WriteApkRepositories(w, distributions.ApkOptions{
+				Repositories: splitList(*components),
+				Top:          *top,
+			})
+		case "repo":
+			return distroMirrors.WriteRepoFile(w, repoOptions)
+		case "make.conf":
+			return distroMirrors.WriteGentooMirrors(w, distributions.MakeConfOptions{Top: *top})
+		case "rsync":
+			return distroMirrors.WriteRsyncUpstreams(w, distributions.RsyncOptions{Top: *top})
+		case "template":
+			return distroMirrors.WriteTemplate(w, distributions.TemplateOptions{Top: *top})
 		}
-	case "json":
-		distroMirrorsJson, _ := json.Marshal(distroMirrors)
-		fmt.Println(string(distroMirrorsJson))
-	case "csv":
-		w := csv.NewWriter(os.Stdout)
-		defer w.Flush()
-		record := []string{"Rank", "Distribution", "Country", "URL", "Avg Time"}
-		if err := w.Write(record); err != nil {
-			fmt.Fprintf(os.Stderr, "error writing record to file: %v\n", err)
-			os.Exit(1)
-		}
-		for i, distroMirror := range distroMirrors.Mirrors {
-			record := []string{fmt.Sprintf("%v", i), distroMirrors.Distribution.Name(), distroMirror.Country, distroMirror.URL.String(), fmt.Sprintf("%v", distroMirror.Statistics.AvgResponseTimeHTTP)}
-			if err := w.Write(record); err != nil {
-				fmt.Fprintf(os.Stderr, "error writing record to file: %v\n", err)
+		return nil
+	}
+
+	// Write package manager configuration
+	if *mode == "apply" {
+		for _, configFile := range configFiles {
+			// Only the mirrors of existing repository files are replaced
+			if configFile.Format == "repo" {
+				repoOptions.Current, err = os.ReadFile(configFile.Path)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					fmt.Fprintf(os.Stderr, "Can not read %v: %v\n", configFile.Path, err)
+					os.Exit(1)
+				}
+			}
+			var content bytes.Buffer
+			if err := writeOutput(&content, configFile.Format); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing configuration: %v\n", err)
+				os.Exit(1)
+			}
+			backup, err := utils.ReplaceFile(configFile.Path, content.Bytes())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can not write %v: %v\n", configFile.Path, err)
 				os.Exit(1)
 			}
+			if len(backup) != 0 {
+				fmt.Fprintf(os.Stderr, "Wrote %v (backup: %v)\n", configFile.Path, backup)
+			} else {
+				fmt.Fprintf(os.Stderr, "Wrote %v\n", configFile.Path)
+			}
 		}
+		return
+	}
+
+	if err := writeOutput(os.Stdout, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output: %v\n", err)
+		os.Exit(1)
 	}
+}
 
+// Splits a comma separated list (empty values are ignored)
+func splitList(list string) []string {
+	values := []string{}
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); len(value) != 0 {
+			values = append(values, value)
+		}
+	}
+	return values
 }
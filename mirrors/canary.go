@@ -0,0 +1,79 @@
+package mirrors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Expected prefixes of canary files
+const (
+	SIGNATURE_PGP_SIGNED = "-----BEGIN PGP SIGNED MESSAGE-----" // e.g. APT InRelease
+	SIGNATURE_GZIP       = "\x1f\x8b"                           // e.g. pacman database, APKINDEX.tar.gz
+	SIGNATURE_XML        = "<?xml"                              // e.g. repodata/repomd.xml
+)
+
+// Known file of a repository, requested to validate that a mirror actually serves the repository
+// (and not an error page, a captive portal or a parked domain)
+type Canary struct {
+	// Path of the file, relative to the mirror URL
+	Path string
+	// Expected prefix of the file content (empty means any content)
+	Signature string
+	// Read the date of the file, for APT (In)Release canaries (see ParseReleaseDate)
+	ReleaseDate bool
+}
+
+// Mirror responded, but does not serve the canary file.
+type CanaryError struct {
+	Path string
+	Err  error
+}
+
+func (e *CanaryError) Error() string {
+	return fmt.Sprintf("invalid canary %v: %v", e.Path, e.Err)
+}
+
+func (e *CanaryError) Unwrap() error { return e.Err }
+
+// Probes the canary file of the mirror once.
+// HTTP mirrors must respond with 200 OK and content starting with the canary signature, or else the mirror is broken.
+// FTP and rsync mirrors are only checked for the file and module respectively (see GetTimingContext).
+// The release date is read from the same response, if requested by the canary (a missing date is not an error).
+func (m Mirror) ProbeCanary(canary Canary) ProbeResult {
+	return m.ProbeCanaryContext(context.Background(), canary)
+}
+
+// Same as ProbeCanary, but the probe is aborted when the context is done.
+// Without a canary path, the mirror URL is probed as in ProbeContext.
+func (m Mirror) ProbeCanaryContext(ctx context.Context, canary Canary) ProbeResult {
+	if len(canary.Path) == 0 {
+		return m.ProbeContext(ctx)
+	}
+	probe := m
+	probe.URL = m.FileURL(canary.Path)
+	var releaseDate time.Time
+	timing, err := probe.getTimingContext(ctx, func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			return &CanaryError{Path: canary.Path, Err: &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}}
+		}
+		prefix := make([]byte, len(canary.Signature))
+		if _, err := io.ReadFull(resp.Body, prefix); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		if string(prefix) != canary.Signature {
+			return &CanaryError{Path: canary.Path, Err: fmt.Errorf("unexpected content (%v)", resp.Header.Get("Content-Type"))}
+		}
+		if canary.ReleaseDate {
+			releaseDate, _ = ParseReleaseDate(io.MultiReader(bytes.NewReader(prefix), resp.Body))
+		}
+		return nil
+	})
+	result := NewProbeResult(err)
+	result.Timing = timing
+	result.ReleaseDate = releaseDate
+	return result
+}
@@ -0,0 +1,63 @@
+package mirrors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeCanaryContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debian/dists/stable/InRelease":
+			w.Write([]byte(SIGNATURE_PGP_SIGNED + "\nHash: SHA512\n\nOrigin: Debian\nDate: Sat, 12 Oct 2024 09:00:00 UTC\n"))
+		case "/parked/dists/stable/InRelease":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>This domain is for sale</body></html>"))
+		case "/portal/dists/stable/InRelease":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			w.Write([]byte("<html><body>Sign in to the network</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	canary := Canary{Path: "dists/stable/InRelease", Signature: SIGNATURE_PGP_SIGNED}
+	newMirror := func(path string) Mirror {
+		u, _ := url.Parse(server.URL + path)
+		return Mirror{URL: u}
+	}
+
+	result := newMirror("/debian/").ProbeCanaryContext(context.Background(), canary)
+	assert.True(t, result.OK(), result.Error)
+	assert.Greater(t, int64(result.Timing.Total), int64(0))
+	assert.True(t, result.ReleaseDate.IsZero())
+
+	// Release date from the same response
+	result = newMirror("/debian/").ProbeCanaryContext(context.Background(), Canary{Path: canary.Path, Signature: canary.Signature, ReleaseDate: true})
+	assert.True(t, result.OK(), result.Error)
+	assert.Equal(t, time.Date(2024, 10, 12, 9, 0, 0, 0, time.UTC), result.ReleaseDate)
+
+	for _, path := range []string{"/parked/", "/portal/"} {
+		result = newMirror(path).ProbeCanaryContext(context.Background(), canary)
+		assert.Equal(t, ProbeBroken, result.Status, path)
+	}
+
+	result = newMirror("/missing/").ProbeCanaryContext(context.Background(), canary)
+	assert.Equal(t, ProbeBroken, result.Status)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	// Any content, as long as the file exists
+	result = newMirror("/parked/").ProbeCanaryContext(context.Background(), Canary{Path: canary.Path})
+	assert.True(t, result.OK())
+
+	// Mirror URL without a canary
+	result = newMirror("/missing/").ProbeCanaryContext(context.Background(), Canary{})
+	assert.Equal(t, ProbeHTTPError, result.Status)
+}
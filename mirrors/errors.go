@@ -0,0 +1,46 @@
+package mirrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when a mirror list was retrieved and parsed, but contained no mirrors.
+var ErrNoMirrors = errors.New("empty mirror list")
+
+// Failure to retrieve a mirror list from its source (network request or file read).
+type FetchError struct {
+	Source string
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("can not fetch mirror list from %v: %v", e.Source, e.Err)
+}
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// Failure to parse a retrieved mirror list (unexpected format or content).
+type ParseError struct {
+	Source string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("can not parse mirror list from %v: %v", e.Source, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Returned when a mirror uses a protocol that can not be probed or downloaded from.
+var ErrUnsupportedProtocol = errors.New("unsupported protocol")
+
+// HTTP response with an unexpected status code.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %v", e.Status)
+}
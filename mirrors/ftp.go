@@ -0,0 +1,162 @@
+package mirrors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Makes an anonymous FTP session to the mirror and returns the timing of each phase.
+// Directories (paths ending with "/") are probed with CWD and files with SIZE,
+// or with RETR of the first byte if the server does not support SIZE.
+// FirstByte is the time until the server greeting, and Total until the reply of the probe.
+func (m Mirror) getFTPTimingContext(ctx context.Context) (HTTPTiming, error) {
+	timing := HTTPTiming{}
+	ctx, cancel := context.WithTimeout(ctx, HTTP_TIMEOUT*time.Second)
+	defer cancel()
+	start := time.Now()
+	conn, err := m.dialContext(ctx, "ftp", &timing)
+	if err != nil {
+		return timing, err
+	}
+	defer conn.Close()
+	// Abort the session when the context is done
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	text := textproto.NewConn(conn)
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return timing, err
+	}
+	timing.FirstByte = time.Since(start)
+	// Login as anonymous (password may not be required)
+	code, _, err := ftpCommand(text, "USER anonymous")
+	if err != nil {
+		return timing, err
+	}
+	if code == 331 {
+		if code, msg, err := ftpCommand(text, "PASS anonymous@"); err != nil {
+			return timing, err
+		} else if code != 230 {
+			return timing, fmt.Errorf("login failed: %v %v", code, msg)
+		}
+	} else if code != 230 {
+		return timing, fmt.Errorf("login failed: %v", code)
+	}
+	if err := m.ftpProbe(ctx, text, conn.RemoteAddr()); err != nil {
+		return timing, err
+	}
+	timing.Total = time.Since(start)
+	ftpCommand(text, "QUIT")
+	return timing, nil
+}
+
+// Checks that the mirror path exists
+func (m Mirror) ftpProbe(ctx context.Context, text *textproto.Conn, addr net.Addr) error {
+	path := m.URL.Path
+	if len(path) == 0 || strings.HasSuffix(path, "/") {
+		code, msg, err := ftpCommand(text, "CWD %v", path)
+		if err != nil {
+			return err
+		}
+		if code != 250 {
+			return fmt.Errorf("can not change directory to %v: %v %v", path, code, msg)
+		}
+		return nil
+	}
+	// SIZE is only defined for binary mode
+	if _, _, err := ftpCommand(text, "TYPE I"); err != nil {
+		return err
+	}
+	code, msg, err := ftpCommand(text, "SIZE %v", path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case code == 213:
+		return nil
+	case code == 500 || code == 502:
+		return ftpRetrieve(ctx, text, addr, path)
+	default:
+		return fmt.Errorf("can not find %v: %v %v", path, code, msg)
+	}
+}
+
+// Retrieves the first byte of a file (passive mode)
+func ftpRetrieve(ctx context.Context, text *textproto.Conn, addr net.Addr, path string) error {
+	code, msg, err := ftpCommand(text, "PASV")
+	if err != nil {
+		return err
+	}
+	if code != 227 {
+		return fmt.Errorf("passive mode failed: %v %v", code, msg)
+	}
+	port, err := parsePASV(msg)
+	if err != nil {
+		return err
+	}
+	// Connect to the advertised port of the control connection host (the advertised address may be private)
+	host, _, _ := net.SplitHostPort(addr.String())
+	dialer := net.Dialer{}
+	data, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		data.SetDeadline(deadline)
+	}
+	code, msg, err = ftpCommand(text, "RETR %v", path)
+	if err != nil {
+		return err
+	}
+	if code != 125 && code != 150 {
+		return fmt.Errorf("can not retrieve %v: %v %v", path, code, msg)
+	}
+	buf := make([]byte, 1)
+	if _, err := data.Read(buf); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Parses the data port of a PASV reply, e.g. "Entering Passive Mode (192,168,1,2,195,80)"
+func parsePASV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid passive mode reply: %v", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("invalid passive mode reply: %v", msg)
+	}
+	p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid passive mode reply: %v", msg)
+	}
+	return p1*256 + p2, nil
+}
+
+// Sends an FTP command and reads its reply (any code)
+func ftpCommand(text *textproto.Conn, format string, args ...interface{}) (int, string, error) {
+	if err := text.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+	return text.ReadResponse(0)
+}
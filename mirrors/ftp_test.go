@@ -0,0 +1,143 @@
+package mirrors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Minimal anonymous FTP server, serving the given files (and their parent directories)
+func newFTPServer(t *testing.T, files map[string]string, size bool) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not start FTP server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFTP(conn, files, size)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFTP(conn net.Conn, files map[string]string, size bool) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var data net.Listener
+	text.PrintfLine("220 Test FTP server")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch command {
+		case "USER":
+			text.PrintfLine("331 Please specify the password")
+		case "PASS":
+			text.PrintfLine("230 Login successful")
+		case "TYPE":
+			text.PrintfLine("200 Switching to Binary mode")
+		case "CWD":
+			found := false
+			for name := range files {
+				found = found || strings.HasPrefix(name, arg)
+			}
+			if found {
+				text.PrintfLine("250 Directory successfully changed")
+			} else {
+				text.PrintfLine("550 Failed to change directory")
+			}
+		case "SIZE":
+			if content, ok := files[arg]; !size {
+				text.PrintfLine("502 Command not implemented")
+			} else if ok {
+				text.PrintfLine("213 %v", len(content))
+			} else {
+				text.PrintfLine("550 Could not get file size")
+			}
+		case "PASV":
+			data, _ = net.Listen("tcp", "127.0.0.1:0")
+			port := data.Addr().(*net.TCPAddr).Port
+			text.PrintfLine("227 Entering Passive Mode (10,0,0,1,%v,%v)", port/256, port%256)
+		case "RETR":
+			content, ok := files[arg]
+			if !ok || data == nil {
+				text.PrintfLine("550 Failed to open file")
+				continue
+			}
+			text.PrintfLine("150 Opening BINARY mode data connection")
+			dataConn, err := data.Accept()
+			if err == nil {
+				fmt.Fprint(dataConn, content)
+				dataConn.Close()
+			}
+			data.Close()
+			text.PrintfLine("226 Transfer complete")
+		case "QUIT":
+			text.PrintfLine("221 Goodbye")
+			return
+		default:
+			text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func TestGetTimingContextFTP(t *testing.T) {
+	files := map[string]string{"/debian/ls-lR.gz": "listing"}
+	addr := newFTPServer(t, files, true)
+
+	// Directory
+	m := Mirror{URL: &url.URL{Scheme: "ftp", Host: addr, Path: "/debian/"}}
+	timing, err := m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+	assert.Greater(t, timing.TCPConnect, time.Duration(0))
+	assert.Greater(t, timing.FirstByte, time.Duration(0))
+	assert.GreaterOrEqual(t, timing.Total, timing.FirstByte)
+	assert.Less(t, m.GetTime(), HTTP_TIMEOUT*time.Second)
+
+	// File
+	m.URL = m.FileURL("ls-lR.gz")
+	_, err = m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+
+	// Missing file and directory
+	m.URL = m.FileURL("missing")
+	_, err = m.GetTimingContext(context.Background())
+	assert.Error(t, err)
+	m.URL.Path = "/ubuntu/"
+	_, err = m.GetTimingContext(context.Background())
+	assert.Error(t, err)
+}
+
+func TestGetTimingContextFTPRetrieve(t *testing.T) {
+	files := map[string]string{"/debian/ls-lR.gz": "listing"}
+	addr := newFTPServer(t, files, false)
+
+	m := Mirror{URL: &url.URL{Scheme: "ftp", Host: addr, Path: "/debian/ls-lR.gz"}}
+	_, err := m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+	m.URL.Path = "/debian/missing"
+	_, err = m.GetTimingContext(context.Background())
+	assert.Error(t, err)
+}
+
+func TestParsePASV(t *testing.T) {
+	port, err := parsePASV("Entering Passive Mode (192,168,1,2,195,80).")
+	assert.NoError(t, err)
+	assert.Equal(t, 195*256+80, port)
+	_, err = parsePASV("Entering Passive Mode")
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package mirrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-ping/ping"
+)
+
+const (
+	LATENCY_TIMEOUT = 3
+)
+
+// Default ports of the mirror protocols
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"rsync": "873",
+}
+
+// Measures the round-trip time to the mirror host without requiring root privileges.
+// The time of a TCP connection to the mirror port is used, or an unprivileged (UDP) ICMP ping if the port is unknown.
+// A host that can not be connected (timeout or refusal) is not pinged, so that dead mirrors fail within LATENCY_TIMEOUT.
+func (m Mirror) GetLatency() (time.Duration, error) {
+	return m.GetLatencyContext(context.Background())
+}
+
+// Same as GetLatency, but probing is aborted when the context is done.
+func (m Mirror) GetLatencyContext(ctx context.Context) (time.Duration, error) {
+	if m.URL == nil || len(m.URL.Hostname()) == 0 {
+		return 0, errors.New("missing mirror host")
+	}
+	rtt, err := m.connectTime(ctx)
+	if !errors.Is(err, ErrUnsupportedProtocol) {
+		return rtt, err
+	}
+	return m.pingContext(ctx, false)
+}
+
+// Time of a TCP connection to the mirror port (host name is resolved before, so that DNS is not measured)
+func (m Mirror) connectTime(ctx context.Context) (time.Duration, error) {
+	if len(m.URL.Port()) == 0 && len(defaultPorts[m.URL.Scheme]) == 0 {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedProtocol, m.URL.Scheme)
+	}
+	ctx, cancel := context.WithTimeout(ctx, LATENCY_TIMEOUT*time.Second)
+	defer cancel()
+	timing := HTTPTiming{}
+	conn, err := m.dialContext(ctx, m.URL.Scheme, &timing)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return timing.TCPConnect, nil
+}
+
+// Pings the mirror host, using raw sockets if privileged, or else UDP ICMP sockets
+// (allowed on Linux for the groups in net.ipv4.ping_group_range)
+func (m Mirror) pingContext(ctx context.Context, privileged bool) (time.Duration, error) {
+	pinger, err := ping.NewPinger(m.URL.Hostname())
+	if err != nil {
+		return 0, err
+	}
+	pinger.Count = 2
+	pinger.Timeout = LATENCY_TIMEOUT * time.Second
+	pinger.SetPrivileged(privileged)
+
+	// Stop pinger early if context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pinger.Stop()
+		case <-done:
+		}
+	}()
+	if err := pinger.Run(); err != nil {
+		return 0, err
+	}
+	stats := pinger.Statistics()
+	if stats.PacketsRecv == 0 {
+		return 0, fmt.Errorf("no replies from %v", m.URL.Hostname())
+	}
+	return stats.AvgRtt, nil
+}
@@ -2,19 +2,19 @@ package mirrors
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"net/http"
-	"net/http/httptrace"
 	"net/url"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/go-ping/ping"
 	"github.com/thanoskoutr/gomirror/utils"
 )
 
@@ -31,6 +31,21 @@ type Mirror struct {
 	Protocol      Protocol          `json:"protocol"`
 	Architectures string            `json:"architectures,omitempty"`
 	Statistics    *MirrorStatistics `json:"statistics,omitempty"`
+	// Status reported by the mirror list of the distribution (if available)
+	LastSync      time.Time     `json:"last_sync,omitempty"`
+	CompletionPct float64       `json:"completion_pct,omitempty"` // 0-1
+	Delay         time.Duration `json:"delay,omitempty"`          // behind upstream
+	Score         float64       `json:"score,omitempty"`          // lower is better (0 if unknown)
+	Active        bool          `json:"active,omitempty"`
+	ISOs          bool          `json:"isos,omitempty"`
+	IPv4          bool          `json:"ipv4,omitempty"`
+	IPv6          bool          `json:"ipv6,omitempty"`
+	Preference    int           `json:"preference,omitempty"` // metalink preference (0-100), higher is better
+	Priority      int           `json:"priority,omitempty"`   // metalink/MirrorCache priority (1 is the highest), lower is better
+	Bandwidth     string        `json:"bandwidth,omitempty"`  // as reported by the mirror list (e.g. "1 Gbps")
+	Region        string        `json:"region,omitempty"`     // e.g. "Europe"
+	Partial       bool          `json:"partial,omitempty"`    // does not carry the whole archive
+	Status        string        `json:"status,omitempty"`     // freshness as reported by the mirror list (e.g. "Up to date")
 }
 
 func (m Mirror) String() string {
@@ -41,12 +56,30 @@ func (m Mirror) String() string {
 }
 
 func (m *Mirror) MarshalJSON() ([]byte, error) {
+	var lastSync *time.Time
+	if !m.LastSync.IsZero() {
+		lastSync = &m.LastSync
+	}
 	return json.Marshal(&struct {
 		Country       string            `json:"country,omitempty"`
 		CountryCode   string            `json:"country_code,omitempty"`
 		URL           string            `json:"url"`
 		Protocol      string            `json:"protocol"`
 		Architectures string            `json:"architectures,omitempty"`
+		LastSync      *time.Time        `json:"last_sync,omitempty"`
+		CompletionPct float64           `json:"completion_pct,omitempty"`
+		Delay         int64             `json:"delay,omitempty"`
+		Score         float64           `json:"score,omitempty"`
+		Active        bool              `json:"active,omitempty"`
+		ISOs          bool              `json:"isos,omitempty"`
+		IPv4          bool              `json:"ipv4,omitempty"`
+		IPv6          bool              `json:"ipv6,omitempty"`
+		Preference    int               `json:"preference,omitempty"`
+		Priority      int               `json:"priority,omitempty"`
+		Bandwidth     string            `json:"bandwidth,omitempty"`
+		Region        string            `json:"region,omitempty"`
+		Partial       bool              `json:"partial,omitempty"`
+		Status        string            `json:"status,omitempty"`
 		Statistics    *MirrorStatistics `json:"statistics,omitempty"`
 	}{
 		Country:       m.Country,
@@ -54,6 +87,20 @@ func (m *Mirror) MarshalJSON() ([]byte, error) {
 		URL:           m.URL.String(),
 		Protocol:      m.URL.Scheme,
 		Architectures: m.Architectures,
+		LastSync:      lastSync,
+		CompletionPct: m.CompletionPct,
+		Delay:         int64(m.Delay.Seconds()),
+		Score:         m.Score,
+		Active:        m.Active,
+		ISOs:          m.ISOs,
+		IPv4:          m.IPv4,
+		IPv6:          m.IPv6,
+		Preference:    m.Preference,
+		Priority:      m.Priority,
+		Bandwidth:     m.Bandwidth,
+		Region:        m.Region,
+		Partial:       m.Partial,
+		Status:        m.Status,
 		Statistics:    m.Statistics,
 	})
 }
@@ -65,15 +112,27 @@ func (m *Mirror) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	if v["country"] != nil {
-		m.Country = v["country"].(string)
+		country, ok := v["country"].(string)
+		if !ok {
+			return fmt.Errorf("invalid mirror country: %v", v["country"])
+		}
+		m.Country = country
 	}
 	if v["country_code"] != nil {
-		m.CountryCode = v["country_code"].(string)
+		countryCode, ok := v["country_code"].(string)
+		if !ok {
+			return fmt.Errorf("invalid mirror country code: %v", v["country_code"])
+		}
+		m.CountryCode = countryCode
 	} else {
 		m.CountryCode = utils.GetCountryCode(m.Country)
 	}
 	if v["url"] != nil {
-		m.URL, err = url.Parse(v["url"].(string))
+		u, ok := v["url"].(string)
+		if !ok {
+			return fmt.Errorf("invalid mirror URL: %v", v["url"])
+		}
+		m.URL, err = url.Parse(u)
 		if err != nil {
 			return err
 		}
@@ -81,7 +140,11 @@ func (m *Mirror) UnmarshalJSON(data []byte) error {
 		m.URL, _ = url.Parse("")
 	}
 	if v["protocol"] != nil {
-		m.Protocol, err = ToProtocol(v["protocol"].(string))
+		protocol, ok := v["protocol"].(string)
+		if !ok {
+			return fmt.Errorf("invalid mirror protocol: %v", v["protocol"])
+		}
+		m.Protocol, err = ToProtocol(protocol)
 		if err != nil {
 			return err
 		}
@@ -91,6 +154,39 @@ func (m *Mirror) UnmarshalJSON(data []byte) error {
 			return err
 		}
 	}
+	if v["architectures"] != nil {
+		m.Architectures, _ = v["architectures"].(string)
+	}
+	// Mirror status (delay in seconds)
+	if lastSync, ok := v["last_sync"].(string); ok {
+		m.LastSync, err = time.Parse(time.RFC3339, lastSync)
+		if err != nil {
+			return err
+		}
+	}
+	if completionPct, ok := v["completion_pct"].(float64); ok {
+		m.CompletionPct = completionPct
+	}
+	if delay, ok := v["delay"].(float64); ok {
+		m.Delay = time.Duration(delay) * time.Second
+	}
+	if score, ok := v["score"].(float64); ok {
+		m.Score = score
+	}
+	m.Active, _ = v["active"].(bool)
+	m.ISOs, _ = v["isos"].(bool)
+	m.IPv4, _ = v["ipv4"].(bool)
+	m.IPv6, _ = v["ipv6"].(bool)
+	if preference, ok := v["preference"].(float64); ok {
+		m.Preference = int(preference)
+	}
+	if priority, ok := v["priority"].(float64); ok {
+		m.Priority = int(priority)
+	}
+	m.Bandwidth, _ = v["bandwidth"].(string)
+	m.Region, _ = v["region"].(string)
+	m.Partial, _ = v["partial"].(bool)
+	m.Status, _ = v["status"].(string)
 	return nil
 }
 
@@ -105,98 +201,126 @@ func (m Mirror) GetStatus() int {
 	if err != nil {
 		log.Fatal("Can not get HTTP status of mirror: ", err)
 	}
+	resp.Body.Close()
 	return resp.StatusCode
 }
 
-// TODO: Clean caches before request (DNS)
-// TODO: Check for server delays (on multiple requests)
-// TODO: Error handling
-// TODO: Handle other protocols
-// TODO: Add errors to separate file
 func (m Mirror) GetTime() time.Duration {
-	// Unsupported Protocols
-	if m.URL.Scheme != "http" && m.URL.Scheme != "https" {
-		// log.Println("Error: Unsupported Protocol: ", m.URL.Scheme)
-		return math.MaxInt64
-	}
-	// Create new request
-	req, err := http.NewRequest("GET", m.URL.String(), nil)
-	if err != nil {
-		// log.Println("Error: Can not create request: ", err)
-		return math.MaxInt64
-	}
-	// Initialize an HTTP tracer
-	trace := &httptrace.ClientTrace{}
-	// Wrap request with the HTTP tracer context
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-	// Make request and keep time
-	client := &http.Client{
-		Timeout: HTTP_TIMEOUT * time.Second,
-	}
-	start := time.Now()
-	_, err = client.Do(req)
-	if err != nil {
-		// log.Println("Error: Can not fetch mirror: ", err)
+	return m.GetTimeContext(context.Background())
+}
+
+// Same as GetTime, but the request is aborted when the context is done.
+// Failures are returned as math.MaxInt64, use ProbeContext to find out why the mirror could not be reached.
+// TODO: Check for server delays (on multiple requests)
+func (m Mirror) GetTimeContext(ctx context.Context) time.Duration {
+	result := m.ProbeContext(ctx)
+	if !result.OK() {
 		return math.MaxInt64
 	}
-	return time.Since(start)
+	return result.Timing.Total
 }
 
-// TODO: Decide on manual approach or use library
-// TODO: Using ping library will require sudo privileges to run program
+// Uses raw ICMP sockets, which require root privileges (see GetLatency for an unprivileged alternative)
 func (m Mirror) Ping() time.Duration {
-	pinger, err := ping.NewPinger(m.URL.Host)
-	if err != nil {
-		log.Println("Error: Can not create pinger: ", err)
-		return 0
-	}
-	pinger.Count = 2
-	pinger.Timeout = 5 * time.Second
-	pinger.SetPrivileged(true)
+	return m.PingContext(context.Background())
+}
 
-	err = pinger.Run()
+// Same as Ping, but pinging stops when the context is done.
+func (m Mirror) PingContext(ctx context.Context) time.Duration {
+	rtt, err := m.pingContext(ctx, true)
 	if err != nil {
 		log.Println("Error: Can not ping target host: ", err)
 	}
 	// TODO: Analyze statistics more: https://pkg.go.dev/github.com/go-ping/ping#Statistics
-	return pinger.Statistics().AvgRtt
+	return rtt
 }
 
-// TODO: Add more fields (httptrace)
 type MirrorStatistics struct {
-	ResponseTimeHTTP    time.Duration
-	ResponseTimePing    time.Duration // AvgRtt
-	AvgResponseTimeHTTP time.Duration
-	Speed               int // in MB/s
+	ResponseTimeHTTP    time.Duration // of the last successful probe
+	ResponseTimePing    time.Duration // average round-trip time (see GetLatency)
+	AvgResponseTimeHTTP time.Duration // of the successful probes
+	Timing              HTTPTiming    // of the last HTTP request
+	Probes              int64         // finished probes
+	Failures            int64         // failed probes (not included in the averages)
+	Probe               ProbeResult   // of the last probe
+	SpeedBps            float64       // download speed in bytes/s (0 if not tested)
+	LastUpdate          time.Time     // date of the mirrored release (zero if unknown)
+	SyncLag             time.Duration // behind the most recent release of all mirrors and upstream
+	RankScore           float64       // blend of mirror status score and response time, lower is better (0 if not ranked)
 }
 
 func (s MirrorStatistics) String() string {
+	if !s.Reachable() {
+		return fmt.Sprintf("{Unreachable: %v}", s.Probe)
+	}
 	return fmt.Sprintf("{ResponseTimeHTTP: %v, AvgResponseTimeHTTP: %v}", s.ResponseTimeHTTP, s.AvgResponseTimeHTTP)
 }
 
+// Reports whether at least one probe succeeded (mirrors that were not probed are considered reachable)
+func (s MirrorStatistics) Reachable() bool {
+	return s.Failures == 0 || s.Failures < s.Probes
+}
+
+// Formats a response time of the mirror ("unreachable" if all probes failed, or "broken" if the last failed validation)
+func (s MirrorStatistics) FormatTime(d time.Duration) string {
+	if !s.Reachable() && s.Probe.Status == ProbeBroken {
+		return "broken"
+	}
+	if !s.Reachable() {
+		return "unreachable"
+	}
+	return d.String()
+}
+
 // TODO: Handle field types for Nanoseconds (int32, float32, string)
 func (s *MirrorStatistics) MarshalJSON() ([]byte, error) {
+	// Omit freshness if it is unknown
+	var lastUpdate *time.Time
+	syncLag := ""
+	if !s.LastUpdate.IsZero() {
+		lastUpdate = &s.LastUpdate
+		syncLag = s.SyncLag.String()
+	}
+	// Omit probe result if not probed
+	var probe *ProbeResult
+	if s.Probes != 0 {
+		probe = &s.Probe
+	}
 	return json.Marshal(&struct {
-		ResponseTimeHTTP    string `json:"http_response,omitempty"`
-		ResponseTimePing    string `json:"ping_response,omitempty"`
-		AvgResponseTimeHTTP string `json:"avg_http_response,omitempty"`
-		Speed               int    `json:"speed,omitempty"`
+		ResponseTimeHTTP    string       `json:"http_response,omitempty"`
+		ResponseTimePing    string       `json:"ping_response,omitempty"`
+		AvgResponseTimeHTTP string       `json:"avg_http_response,omitempty"`
+		Timing              *HTTPTiming  `json:"http_timing,omitempty"`
+		SpeedBps            float64      `json:"speed_bps,omitempty"`
+		LastUpdate          *time.Time   `json:"last_update,omitempty"`
+		SyncLag             string       `json:"sync_lag,omitempty"`
+		RankScore           float64      `json:"rank_score,omitempty"`
+		Probes              int64        `json:"probes,omitempty"`
+		Failures            int64        `json:"failures,omitempty"`
+		Probe               *ProbeResult `json:"probe,omitempty"`
 	}{
-		ResponseTimeHTTP:    s.ResponseTimeHTTP.String(),
+		ResponseTimeHTTP:    s.FormatTime(s.ResponseTimeHTTP),
 		ResponseTimePing:    s.ResponseTimePing.String(),
-		AvgResponseTimeHTTP: s.AvgResponseTimeHTTP.String(),
-		Speed:               s.Speed,
+		AvgResponseTimeHTTP: s.FormatTime(s.AvgResponseTimeHTTP),
+		Timing:              &s.Timing,
+		SpeedBps:            s.SpeedBps,
+		LastUpdate:          lastUpdate,
+		SyncLag:             syncLag,
+		RankScore:           s.RankScore,
+		Probes:              s.Probes,
+		Failures:            s.Failures,
+		Probe:               probe,
 	})
 }
 
-// Examples: HTTP (80), HTTPS (443), FTP (21), Rsync (22)
+// Default port of the protocol: HTTP (80), HTTPS (443), FTP (21), Rsync (873)
 type Protocol uint16
 
 const (
 	ProtoHTTP  Protocol = 80
 	ProtoHTTPS Protocol = 443
 	ProtoFTP   Protocol = 21
-	ProtoRSYNC Protocol = 22
+	ProtoRSYNC Protocol = 873
 )
 
 func (p Protocol) String() string {
@@ -229,13 +353,14 @@ func ToProtocol(proto string) (Protocol, error) {
 	}
 }
 
-// Supported sources: TXT, JSON, HTTP
+// Supported sources: TXT, JSON, HTTP, Backup (snapshot embedded in the binary)
 type MirrorSource int
 
 const (
 	SourceHTTP MirrorSource = iota
 	SourceJSON
 	SourceTXT
+	SourceBackup
 )
 
 func (s MirrorSource) String() string {
@@ -246,6 +371,8 @@ func (s MirrorSource) String() string {
 		return "json"
 	case SourceTXT:
 		return "txt"
+	case SourceBackup:
+		return "backup"
 	default:
 		return fmt.Sprintf("%d", s)
 	}
@@ -259,6 +386,8 @@ func ToMirrorSource(source string) (MirrorSource, error) {
 		return SourceJSON, nil
 	case "txt":
 		return SourceTXT, nil
+	case "backup":
+		return SourceBackup, nil
 	default:
 		return -1, fmt.Errorf("unsupported source type: %v", source)
 	}
@@ -266,53 +395,61 @@ func ToMirrorSource(source string) (MirrorSource, error) {
 
 // Read URL mirrors from JSON file
 // TODO: File location
-func ReadMirrorsJSON(filename string) []Mirror {
+func ReadMirrorsJSON(filename string) ([]Mirror, error) {
 	// Open file
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatal("Can not read mirrors: ", err)
+		return nil, &FetchError{Source: filename, Err: err}
 	}
 	defer file.Close()
 	// Read whole file
 	mirrorsBytes, err := io.ReadAll(file)
 	if err != nil {
-		log.Fatal("Can not read mirrors: ", err)
+		return nil, &FetchError{Source: filename, Err: err}
 	}
+	return ParseMirrorsJSON(mirrorsBytes, filename)
+}
+
+// Parses URL mirrors from JSON (in the same format as ReadMirrorsJSON), read from source
+func ParseMirrorsJSON(mirrorsBytes []byte, source string) ([]Mirror, error) {
 	// Parse file as JSON
 	var mirrorsJson map[string]interface{}
-	err = json.Unmarshal(mirrorsBytes, &mirrorsJson)
+	err := json.Unmarshal(mirrorsBytes, &mirrorsJson)
 	if err != nil {
-		log.Fatal("Can not parse mirrors list: ", err)
+		return nil, &ParseError{Source: source, Err: err}
 	}
 	// Get URLs array
-	if mirrorsJson["urls"] == nil {
-		log.Fatalf("Can not parse mirrors list: %v", mirrorsJson)
+	urls, ok := mirrorsJson["urls"].([]interface{})
+	if !ok {
+		return nil, &ParseError{Source: source, Err: errors.New("missing \"urls\" array")}
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoMirrors
 	}
-	urls := mirrorsJson["urls"].([]interface{})
 	// Create Mirrors array
 	mirrorsList := make([]Mirror, len(urls))
 	// Convert URLs object back to JSON
 	urlsMarshaled, err := json.Marshal(urls)
 	if err != nil {
-		log.Fatal("Can not parse mirrors url list: ", err)
+		return nil, &ParseError{Source: source, Err: err}
 	}
 	// Parse URLs as Mirrors array
 	err = json.Unmarshal(urlsMarshaled, &mirrorsList)
 	if err != nil {
-		log.Fatal("Can not parse mirrors list: ", err)
+		return nil, &ParseError{Source: source, Err: err}
 	}
-	return mirrorsList
+	return mirrorsList, nil
 }
 
 // Read URL mirrors from TXT file
 // TODO: File location
-func ReadMirrorsTXT(filename string) []Mirror {
+func ReadMirrorsTXT(filename string) ([]Mirror, error) {
 	// Definitions
 	urls := []string{}
 	// Open file
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatal("Can not read mirrors: ", err)
+		return nil, &FetchError{Source: filename, Err: err}
 	}
 	defer file.Close()
 	// Read file line-by-line
@@ -323,7 +460,10 @@ func ReadMirrorsTXT(filename string) []Mirror {
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		log.Fatal("Can not read mirrors:", err)
+		return nil, &FetchError{Source: filename, Err: err}
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoMirrors
 	}
 
 	// Create Mirrors array
@@ -340,5 +480,5 @@ func ReadMirrorsTXT(filename string) []Mirror {
 			URL: urlStr,
 		}
 	}
-	return mirrors
+	return mirrors, nil
 }
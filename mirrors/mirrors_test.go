@@ -1,8 +1,12 @@
 package mirrors
 
 import (
+	"context"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -47,19 +51,141 @@ func TestReadMirrorsJSON(t *testing.T) {
 			Protocol:    21,
 		},
 	}
-	actual := ReadMirrorsJSON(input)
+	actual, err := ReadMirrorsJSON(input)
+	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
 
+func TestReadMirrorsErrors(t *testing.T) {
+	var fetchErr *FetchError
+	_, err := ReadMirrorsJSON("../inputs/does-not-exist.json")
+	assert.ErrorAs(t, err, &fetchErr)
+	_, err = ReadMirrorsTXT("../inputs/does-not-exist.txt")
+	assert.ErrorAs(t, err, &fetchErr)
+
+	var parseErr *ParseError
+	_, err = ReadMirrorsJSON("../inputs/in.template.txt")
+	assert.ErrorAs(t, err, &parseErr)
+	// Fields of the wrong type are parse errors (not panics)
+	for _, input := range []string{`{"urls":[{"url": 5}]}`, `{"urls":[{"url": "http://a/", "country": true}]}`, `{"urls":[{"url": "http://a/", "protocol": 80}]}`, `{"urls":[{"url": "http://a/", "country_code": []}]}`} {
+		_, err = ParseMirrorsJSON([]byte(input), "test")
+		assert.ErrorAs(t, err, &parseErr, input)
+	}
+
+	_, err = ReadMirrorsTXT("../inputs/custom.txt")
+	assert.ErrorIs(t, err, ErrNoMirrors)
+}
+
 func TestGetTime(t *testing.T) {
-	input := ReadMirrorsJSON("../inputs/in.template.json")
+	input, err := ReadMirrorsJSON("../inputs/in.template.json")
+	assert.NoError(t, err)
 	expected := time.Duration(math.MaxInt64)
 	actual := time.Duration(0)
 
 	for _, m := range input {
 		actual = m.GetTime()
-		if m.URL.Scheme != "http" && m.URL.Scheme != "https" {
+		if m.URL.Scheme != "http" && m.URL.Scheme != "https" && m.URL.Scheme != "ftp" && m.URL.Scheme != "rsync" {
 			assert.Equal(t, expected, actual)
 		}
 	}
 }
+
+func TestGetTimeContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	m := Mirror{URL: serverURL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	actual := m.GetTimeContext(ctx)
+	assert.Equal(t, time.Duration(math.MaxInt64), actual)
+	assert.Less(t, time.Since(start), HTTP_TIMEOUT*time.Second)
+}
+
+func TestGetTimingContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	m := Mirror{URL: serverURL}
+
+	timing, err := m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+	assert.Greater(t, timing.TCPConnect, time.Duration(0))
+	assert.Equal(t, time.Duration(0), timing.TLSHandshake)
+	assert.GreaterOrEqual(t, timing.FirstByte, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, timing.Total, timing.FirstByte)
+}
+
+func TestGetLatencyContext(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	m := Mirror{URL: serverURL}
+
+	// Only a TCP connection is made
+	rtt, err := m.GetLatencyContext(context.Background())
+	assert.NoError(t, err)
+	assert.Greater(t, rtt, time.Duration(0))
+	assert.Less(t, rtt, LATENCY_TIMEOUT*time.Second)
+	server.Close()
+	assert.Equal(t, 0, requests)
+
+	// Refused connections are not retried with other methods
+	start := time.Now()
+	_, err = m.GetLatencyContext(context.Background())
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), LATENCY_TIMEOUT*time.Second)
+
+	_, err = Mirror{URL: &url.URL{}}.GetLatencyContext(context.Background())
+	assert.Error(t, err)
+}
+
+func TestGetSpeedContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ubuntu/ls-lR.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(make([]byte, 1024*1024))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/ubuntu")
+	m := Mirror{URL: serverURL}
+
+	speed, err := m.GetSpeedContext(context.Background(), "ls-lR.gz", 1024*1024, time.Second)
+	assert.NoError(t, err)
+	assert.Greater(t, speed, float64(0))
+
+	_, err = m.GetSpeedContext(context.Background(), "missing", 1024*1024, time.Second)
+	assert.Error(t, err)
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	release := `Origin: Ubuntu
+Label: Ubuntu
+Suite: noble
+Version: 24.04
+Codename: noble
+Date: Thu, 25 Apr 2024 15:10:33 UTC
+Architectures: amd64 arm64 armhf i386 ppc64el riscv64 s390x
+Components: main restricted universe multiverse
+MD5Sum:
+ 9eb8a4ac45d7b6d2e0e1ae42d5f2e3b4   1387 main/binary-amd64/Release
+`
+	expected := time.Date(2024, time.April, 25, 15, 10, 33, 0, time.UTC)
+	actual, err := ParseReleaseDate(strings.NewReader(release))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	_, err = ParseReleaseDate(strings.NewReader("Origin: Debian\nMD5Sum:\n Date: none\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package mirrors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Outcome of a mirror probe
+type ProbeStatus int
+
+const (
+	ProbeOK ProbeStatus = iota
+	ProbeTimeout
+	ProbeDNSError
+	ProbeConnectionError // refused, reset or unreachable
+	ProbeTLSError
+	ProbeHTTPError // 4xx/5xx response
+	ProbeUnsupportedProtocol
+	ProbeBroken // responds, but does not serve the repository (see Canary)
+	ProbeCanceled
+	ProbeError // any other failure (e.g. protocol error)
+)
+
+func (s ProbeStatus) String() string {
+	switch s {
+	case ProbeOK:
+		return "ok"
+	case ProbeTimeout:
+		return "timeout"
+	case ProbeDNSError:
+		return "dns error"
+	case ProbeConnectionError:
+		return "connection error"
+	case ProbeTLSError:
+		return "tls error"
+	case ProbeHTTPError:
+		return "http error"
+	case ProbeUnsupportedProtocol:
+		return "unsupported protocol"
+	case ProbeBroken:
+		return "broken"
+	case ProbeCanceled:
+		return "canceled"
+	case ProbeError:
+		return "error"
+	default:
+		return fmt.Sprintf("%d", s)
+	}
+}
+
+func ToProbeStatus(status string) (ProbeStatus, error) {
+	for s := ProbeOK; s <= ProbeError; s++ {
+		if strings.EqualFold(status, s.String()) {
+			return s, nil
+		}
+	}
+	return -1, fmt.Errorf("unsupported probe status: %v", status)
+}
+
+// Result of probing a mirror once
+type ProbeResult struct {
+	Status     ProbeStatus
+	Error      string     // empty if the probe succeeded
+	StatusCode int        // HTTP status code (0 if there was no HTTP response)
+	Timing     HTTPTiming // of the probe (phases that did not finish are 0)
+	// Date of the APT release served by the mirror, if read from the canary (zero if unknown)
+	ReleaseDate time.Time
+}
+
+func (r ProbeResult) OK() bool { return r.Status == ProbeOK }
+
+func (r ProbeResult) String() string {
+	if r.OK() {
+		return fmt.Sprintf("{Status: %v, Total: %v}", r.Status, r.Timing.Total)
+	}
+	return fmt.Sprintf("{Status: %v, Error: %v}", r.Status, r.Error)
+}
+
+// Timing is omitted, since it is written along with the statistics
+func (r *ProbeResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Status     string `json:"status"`
+		Error      string `json:"error,omitempty"`
+		StatusCode int    `json:"status_code,omitempty"`
+	}{
+		Status:     r.Status.String(),
+		Error:      r.Error,
+		StatusCode: r.StatusCode,
+	})
+}
+
+// Probes the mirror once (see GetTimingContext) and classifies the outcome
+func (m Mirror) Probe() ProbeResult {
+	return m.ProbeContext(context.Background())
+}
+
+// Same as Probe, but the probe is aborted when the context is done.
+func (m Mirror) ProbeContext(ctx context.Context) ProbeResult {
+	timing, err := m.GetTimingContext(ctx)
+	result := NewProbeResult(err)
+	result.Timing = timing
+	return result
+}
+
+// Classifies the error of a probe (nil means success)
+func NewProbeResult(err error) ProbeResult {
+	if err == nil {
+		return ProbeResult{Status: ProbeOK}
+	}
+	result := ProbeResult{Status: ProbeError, Error: err.Error()}
+	var statusErr *HTTPStatusError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	var opErr *net.OpError
+	var canaryErr *CanaryError
+	if errors.As(err, &statusErr) {
+		result.StatusCode = statusErr.StatusCode
+	}
+	switch {
+	case errors.As(err, &canaryErr):
+		result.Status = ProbeBroken
+	case statusErr != nil:
+		result.Status = ProbeHTTPError
+	case errors.Is(err, ErrUnsupportedProtocol):
+		result.Status = ProbeUnsupportedProtocol
+	case errors.As(err, &dnsErr):
+		result.Status = ProbeDNSError
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		result.Status = ProbeTimeout
+	case errors.Is(err, context.Canceled):
+		result.Status = ProbeCanceled
+	case errors.As(err, &recordErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &certErr),
+		strings.Contains(err.Error(), "tls: "):
+		result.Status = ProbeTLSError
+	case errors.As(err, &opErr):
+		result.Status = ProbeConnectionError
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package mirrors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing/" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/debian/")
+	m := Mirror{URL: serverURL}
+
+	result := m.ProbeContext(context.Background())
+	assert.True(t, result.OK())
+	assert.Empty(t, result.Error)
+	assert.Greater(t, int64(result.Timing.Total), int64(0))
+
+	m.URL = m.FileURL("../missing/")
+	result = m.ProbeContext(context.Background())
+	assert.Equal(t, ProbeHTTPError, result.Status)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	assert.Equal(t, "unexpected HTTP status: 404 Not Found", result.Error)
+
+	// Closed port
+	server.Close()
+	result = m.ProbeContext(context.Background())
+	assert.Equal(t, ProbeConnectionError, result.Status)
+
+	m.URL = &url.URL{Scheme: "gopher", Host: "example.org"}
+	result = m.ProbeContext(context.Background())
+	assert.Equal(t, ProbeUnsupportedProtocol, result.Status)
+}
+
+func TestNewProbeResult(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ProbeStatus
+	}{
+		{nil, ProbeOK},
+		{&url.Error{Op: "Get", URL: "http://example.org", Err: &net.DNSError{Err: "no such host", Name: "example.org"}}, ProbeDNSError},
+		{fmt.Errorf("dial: %w", context.DeadlineExceeded), ProbeTimeout},
+		{context.Canceled, ProbeCanceled},
+		{errors.New("remote error: tls: handshake failure"), ProbeTLSError},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ProbeConnectionError},
+		{errors.New("unexpected rsync greeting"), ProbeError},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, NewProbeResult(test.err).Status, "%v", test.err)
+	}
+}
+
+func TestToProbeStatus(t *testing.T) {
+	for s := ProbeOK; s <= ProbeError; s++ {
+		status, err := ToProbeStatus(s.String())
+		assert.NoError(t, err)
+		assert.Equal(t, s, status)
+	}
+	_, err := ToProbeStatus("slow")
+	assert.Error(t, err)
+}
+
+func TestMirrorStatisticsUnreachable(t *testing.T) {
+	stats := MirrorStatistics{Probes: 2, Failures: 2, Probe: ProbeResult{Status: ProbeHTTPError, Error: "unexpected HTTP status: 404 Not Found", StatusCode: 404}}
+	assert.False(t, stats.Reachable())
+	data, err := json.Marshal(&stats)
+	assert.NoError(t, err)
+	var v map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &v))
+	assert.Equal(t, "unreachable", v["avg_http_response"])
+	assert.Equal(t, float64(2), v["failures"])
+	assert.Equal(t, map[string]interface{}{"status": "http error", "error": "unexpected HTTP status: 404 Not Found", "status_code": float64(404)}, v["probe"])
+
+	// Some probes succeeded
+	stats.Failures = 1
+	assert.True(t, stats.Reachable())
+	assert.True(t, MirrorStatistics{}.Reachable())
+}
@@ -0,0 +1,63 @@
+package mirrors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Reads the "Date" field of an APT Release (or InRelease) file.
+// Only the header of the file is read, up to the "Date" field.
+func ParseReleaseDate(r io.Reader) (time.Time, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// The header ends at the first file checksum list
+		if strings.HasPrefix(line, " ") {
+			break
+		}
+		if !strings.HasPrefix(line, "Date:") {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(line, "Date:"))
+		for _, layout := range []string{time.RFC1123, time.RFC1123Z} {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid Release date: %v", value)
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errors.New("no Date field in Release file")
+}
+
+// Fetches an APT Release file from the mirror (path is relative to the mirror URL) and returns its date.
+func (m Mirror) GetReleaseDateContext(ctx context.Context, path string) (time.Time, error) {
+	// Unsupported Protocols
+	if m.URL.Scheme != "http" && m.URL.Scheme != "https" {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrUnsupportedProtocol, m.URL.Scheme)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", m.FileURL(path).String(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	client := &http.Client{
+		Timeout: HTTP_TIMEOUT * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return ParseReleaseDate(resp.Body)
+}
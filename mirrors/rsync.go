@@ -0,0 +1,87 @@
+package mirrors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Protocol version announced to rsync daemons
+	RSYNC_PROTOCOL_VERSION = "30.0"
+)
+
+// Makes an rsync daemon handshake with the mirror and returns the timing of each phase.
+// The module of the mirror path (e.g. "debian" of rsync://host/debian/) is requested,
+// or the modules are listed if the path is empty.
+// FirstByte is the time until the server greeting, and Total until the module is accepted (or listed).
+func (m Mirror) getRsyncTimingContext(ctx context.Context) (HTTPTiming, error) {
+	timing := HTTPTiming{}
+	ctx, cancel := context.WithTimeout(ctx, HTTP_TIMEOUT*time.Second)
+	defer cancel()
+	start := time.Now()
+	conn, err := m.dialContext(ctx, "rsync", &timing)
+	if err != nil {
+		return timing, err
+	}
+	defer conn.Close()
+	// Abort the handshake when the context is done
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	reader := bufio.NewReader(conn)
+	// Greeting is "@RSYNCD: <version>" (optionally followed by the supported digests)
+	greeting, err := reader.ReadString('\n')
+	if err != nil {
+		return timing, err
+	}
+	if !strings.HasPrefix(greeting, "@RSYNCD: ") {
+		return timing, fmt.Errorf("unexpected rsync greeting: %q", strings.TrimSpace(greeting))
+	}
+	timing.FirstByte = time.Since(start)
+	module := RsyncModule(m.URL.Path)
+	if _, err := fmt.Fprintf(conn, "@RSYNCD: %v\n%v\n", RSYNC_PROTOCOL_VERSION, module); err != nil {
+		return timing, err
+	}
+	// Skip message of the day, until the reply to the module request
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return timing, err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "@RSYNCD: OK" && len(module) != 0:
+		case line == "@RSYNCD: EXIT" && len(module) == 0:
+		case strings.HasPrefix(line, "@RSYNCD: AUTHREQD"):
+			return timing, fmt.Errorf("module %v requires authentication", module)
+		case strings.HasPrefix(line, "@ERROR"):
+			return timing, errors.New(strings.TrimSpace(strings.TrimPrefix(line, "@ERROR:")))
+		case line == "@RSYNCD: EXIT":
+			return timing, fmt.Errorf("module %v not found", module)
+		default:
+			continue
+		}
+		timing.Total = time.Since(start)
+		return timing, nil
+	}
+}
+
+// Returns the rsync module of a mirror path (its first path element)
+func RsyncModule(path string) string {
+	module, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return module
+}
@@ -0,0 +1,97 @@
+package mirrors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Minimal rsync daemon, accepting the given modules (modules starting with "private" require authentication)
+func newRsyncServer(t *testing.T, modules ...string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not start rsync server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRsync(conn, modules)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveRsync(conn net.Conn, modules []string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "@RSYNCD: 31.0 sha512 sha256 md5\n")
+	if _, err := reader.ReadString('\n'); err != nil {
+		return
+	}
+	module, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	module = strings.TrimSpace(module)
+	fmt.Fprint(conn, "Welcome to the test mirror\n\n")
+	if len(module) == 0 {
+		for _, name := range modules {
+			fmt.Fprintf(conn, "%-15s\tTest module\n", name)
+		}
+		fmt.Fprint(conn, "@RSYNCD: EXIT\n")
+		return
+	}
+	for _, name := range modules {
+		if name == module && strings.HasPrefix(name, "private") {
+			fmt.Fprint(conn, "@RSYNCD: AUTHREQD challenge\n")
+			return
+		}
+		if name == module {
+			fmt.Fprint(conn, "@RSYNCD: OK\n")
+			return
+		}
+	}
+	fmt.Fprintf(conn, "@ERROR: Unknown module '%v'\n", module)
+}
+
+func TestGetTimingContextRsync(t *testing.T) {
+	addr := newRsyncServer(t, "debian", "private")
+
+	m := Mirror{URL: &url.URL{Scheme: "rsync", Host: addr, Path: "/debian/"}}
+	timing, err := m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+	assert.Greater(t, timing.TCPConnect, time.Duration(0))
+	assert.Greater(t, timing.FirstByte, time.Duration(0))
+	assert.GreaterOrEqual(t, timing.Total, timing.FirstByte)
+	assert.Less(t, m.GetTime(), HTTP_TIMEOUT*time.Second)
+
+	// Module listing
+	m.URL.Path = ""
+	_, err = m.GetTimingContext(context.Background())
+	assert.NoError(t, err)
+
+	// Unknown and private modules
+	m.URL.Path = "/ubuntu/"
+	_, err = m.GetTimingContext(context.Background())
+	assert.EqualError(t, err, "Unknown module 'ubuntu'")
+	m.URL.Path = "/private/"
+	_, err = m.GetTimingContext(context.Background())
+	assert.Error(t, err)
+}
+
+func TestRsyncModule(t *testing.T) {
+	assert.Equal(t, "debian", RsyncModule("/debian/"))
+	assert.Equal(t, "debian", RsyncModule("debian/dists/"))
+	assert.Equal(t, "", RsyncModule("/"))
+}
@@ -0,0 +1,80 @@
+package mirrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Returns the URL of a file relative to the mirror URL
+func (m Mirror) FileURL(path string) *url.URL {
+	base := *m.URL
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	return base.ResolveReference(&url.URL{Path: strings.TrimPrefix(path, "/")})
+}
+
+// Downloads a file from the mirror (path is relative to the mirror URL) and returns the download speed in bytes/s.
+// The download stops after maxBytes are read or maxDuration has passed (whichever comes first).
+// Only the transfer of the response body is timed, not the connection setup.
+func (m Mirror) GetSpeedContext(ctx context.Context, path string, maxBytes int64, maxDuration time.Duration) (float64, error) {
+	// Unsupported Protocols
+	if m.URL.Scheme != "http" && m.URL.Scheme != "https" {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedProtocol, m.URL.Scheme)
+	}
+	// Limit connection setup time separately from download time
+	ctx, cancel := context.WithTimeout(ctx, HTTP_TIMEOUT*time.Second+maxDuration)
+	defer cancel()
+	// Create new request
+	req, err := http.NewRequestWithContext(ctx, "GET", m.FileURL(path).String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = HTTP_TIMEOUT * time.Second
+	client := &http.Client{
+		Transport: transport,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	// Read body until the limits are reached
+	body := &countingReader{r: resp.Body}
+	start := time.Now()
+	timer := time.AfterFunc(maxDuration, func() { resp.Body.Close() })
+	defer timer.Stop()
+	_, err = io.CopyN(io.Discard, body, maxBytes)
+	elapsed := time.Since(start)
+	// Reaching the end of file or the time limit is not an error
+	if err != nil && !errors.Is(err, io.EOF) && elapsed < maxDuration {
+		return 0, err
+	}
+	if body.n == 0 || elapsed <= 0 {
+		return 0, errors.New("no data downloaded")
+	}
+	return float64(body.n) / elapsed.Seconds(), nil
+}
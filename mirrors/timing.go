@@ -0,0 +1,174 @@
+package mirrors
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breakdown of an HTTP request, as reported by httptrace (or of an FTP session).
+// Phases that did not take place (e.g. TLS handshake for HTTP) are 0.
+type HTTPTiming struct {
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	FirstByte    time.Duration // from the start of the request
+	Total        time.Duration // until the response headers are read
+}
+
+func (t HTTPTiming) String() string {
+	return fmt.Sprintf("{DNSLookup: %v, TCPConnect: %v, TLSHandshake: %v, FirstByte: %v, Total: %v}", t.DNSLookup, t.TCPConnect, t.TLSHandshake, t.FirstByte, t.Total)
+}
+
+func (t *HTTPTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		DNSLookup    string `json:"dns_lookup"`
+		TCPConnect   string `json:"tcp_connect"`
+		TLSHandshake string `json:"tls_handshake"`
+		FirstByte    string `json:"first_byte"`
+		Total        string `json:"total"`
+	}{
+		DNSLookup:    t.DNSLookup.String(),
+		TCPConnect:   t.TCPConnect.String(),
+		TLSHandshake: t.TLSHandshake.String(),
+		FirstByte:    t.FirstByte.String(),
+		Total:        t.Total.String(),
+	})
+}
+
+// Makes an HTTP GET request to the mirror and returns the timing of each request phase.
+// A new connection is used for every request, so that DNS lookup and connection times are always measured.
+// HTTP responses with 4xx/5xx status codes are returned as HTTPStatusError (along with the timing).
+// FTP mirrors are probed with an anonymous FTP session, and rsync mirrors with an rsync daemon handshake instead.
+// TODO: Clean caches before request (DNS)
+func (m Mirror) GetTimingContext(ctx context.Context) (HTTPTiming, error) {
+	return m.getTimingContext(ctx, nil)
+}
+
+// Same as GetTimingContext, but HTTP responses are checked by validate instead (if not nil)
+func (m Mirror) getTimingContext(ctx context.Context, validate func(resp *http.Response) error) (HTTPTiming, error) {
+	timing := HTTPTiming{}
+	switch m.URL.Scheme {
+	case "ftp":
+		return m.getFTPTimingContext(ctx)
+	case "rsync":
+		return m.getRsyncTimingContext(ctx)
+	}
+	// Unsupported Protocols
+	if m.URL.Scheme != "http" && m.URL.Scheme != "https" {
+		return timing, fmt.Errorf("%w: %v", ErrUnsupportedProtocol, m.URL.Scheme)
+	}
+	// Create new request
+	req, err := http.NewRequestWithContext(ctx, "GET", m.URL.String(), nil)
+	if err != nil {
+		return timing, err
+	}
+	// Validate the content as stored (compressed files are not decompressed transparently)
+	if validate != nil {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	// Initialize an HTTP tracer
+	// (connections may be dialed in parallel, e.g. for IPv4 and IPv6)
+	var mu sync.Mutex
+	var start, dnsStart, connectStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.DNSLookup = time.Since(dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && timing.TCPConnect == 0 {
+				timing.TCPConnect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.TLSHandshake = time.Since(tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.FirstByte = time.Since(start)
+		},
+	}
+	// Wrap request with the HTTP tracer context
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	// Do not reuse connections between requests
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   HTTP_TIMEOUT * time.Second,
+	}
+	// Make request and keep time
+	start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return timing, err
+	}
+	defer resp.Body.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	timing.Total = time.Since(start)
+	if validate != nil {
+		return timing, validate(resp)
+	}
+	// Mirror is reachable, but can not serve the requested path
+	if resp.StatusCode >= 400 {
+		return timing, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return timing, nil
+}
+
+// Resolves the mirror host and connects to its port (or the default port of the protocol),
+// filling in the DNS lookup and TCP connect times
+func (m Mirror) dialContext(ctx context.Context, scheme string, timing *HTTPTiming) (net.Conn, error) {
+	port := m.URL.Port()
+	if len(port) == 0 {
+		port = defaultPorts[scheme]
+	}
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, m.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %v", m.URL.Hostname())
+	}
+	timing.DNSLookup = time.Since(start)
+	dialer := net.Dialer{}
+	start = time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addrs[0].String(), port))
+	if err != nil {
+		return nil, err
+	}
+	timing.TCPConnect = time.Since(start)
+	return conn, nil
+}
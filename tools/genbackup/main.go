@@ -0,0 +1,68 @@
+// Generates the backup snapshots of the mirror lists of the supported distributions,
+// embedded in the distributions package. Run with "go generate ./distributions".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thanoskoutr/gomirror/distributions"
+	"github.com/thanoskoutr/gomirror/mirrors"
+	"github.com/thanoskoutr/gomirror/utils"
+)
+
+func main() {
+	var (
+		out     = flag.String("out", "backup", "The directory of the snapshots")
+		distros = flag.String("distros", strings.Join(distributions.SupportedDistributions, ","), "Comma separated distributions to refresh")
+		timeout = flag.Duration("timeout", time.Minute, "Time limit for fetching each mirror list")
+	)
+	flag.Parse()
+
+	failed := false
+	for _, name := range strings.Split(*distros, ",") {
+		distro, err := distributions.ToDistribution(strings.TrimSpace(name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		// Snapshots are taken for the default release and architecture
+		if releaser, ok := distro.(distributions.Releaser); ok {
+			distro = releaser.WithRelease("")
+		}
+		if multiArch, ok := distro.(distributions.MultiArch); ok {
+			distro = multiArch.WithArchitecture("")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		mirrorsList, err := distro.(distributions.ContextDistributor).GetMirrorsContext(ctx, mirrors.SourceHTTP, "")
+		cancel()
+		if err != nil {
+			// Keep the previous snapshot
+			fmt.Fprintf(os.Stderr, "%v: %v\n", distro.Name(), err)
+			failed = true
+			continue
+		}
+		if err := writeBackup(filepath.Join(*out, distributions.BackupFilename(distro)), distributions.NewBackup(distro, mirrorsList, time.Now())); err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", distro.Name(), err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%v: %v mirrors\n", distro.Name(), len(mirrorsList))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func writeBackup(filename string, backup distributions.Backup) error {
+	var content strings.Builder
+	if err := backup.Write(&content); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filename, []byte(content.String()), 0644)
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Name of the cache directory, under the user cache directory ($XDG_CACHE_HOME or ~/.cache)
+	CACHE_DIR_NAME = "gomirror"
+	// Time that cached responses are used without revalidation
+	CACHE_DEFAULT_TTL = 24 * time.Hour
+)
+
+// On-disk cache of HTTP responses. Fresh responses (younger than the TTL) are used as is,
+// older ones are revalidated with conditional requests (ETag and Last-Modified).
+type Cache struct {
+	// Directory of the cached responses
+	Dir string
+	// Time that cached responses are used without revalidation
+	TTL time.Duration
+	// Revalidate all cached responses, regardless of their age
+	Refresh bool
+}
+
+// Metadata of a cached response
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+type cacheKey struct{}
+
+// Returns the default cache directory ($XDG_CACHE_HOME/gomirror)
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CACHE_DIR_NAME), nil
+}
+
+// Returns a copy of the context, with which GetRequestContext uses the cache
+func WithCache(ctx context.Context, cache *Cache) context.Context {
+	return context.WithValue(ctx, cacheKey{}, cache)
+}
+
+// Returns the cache of the context (nil if there is none)
+func CacheFromContext(ctx context.Context) *Cache {
+	cache, _ := ctx.Value(cacheKey{}).(*Cache)
+	return cache
+}
+
+// Paths of the metadata and body of the cached response of a URL
+func (c *Cache) paths(URL string) (string, string) {
+	sum := sha256.Sum256([]byte(URL))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.Dir, name+".json"), filepath.Join(c.Dir, name+".body")
+}
+
+// Returns the cached response of a URL (nil entry if there is none)
+func (c *Cache) load(URL string) (*cacheEntry, []byte) {
+	metaPath, bodyPath := c.paths(URL)
+	meta, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(meta, entry); err != nil || entry.URL != URL {
+		return nil, nil
+	}
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil
+	}
+	return entry, body
+}
+
+// Stores the response of a URL (the body is not rewritten if it is nil)
+func (c *Cache) store(entry *cacheEntry, body []byte) error {
+	metaPath, bodyPath := c.paths(entry.URL)
+	if body != nil {
+		if err := WriteFileAtomic(bodyPath, body, 0644); err != nil {
+			return err
+		}
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(metaPath, meta, 0644)
+}
+
+// Whether a cached response can be used without revalidation
+func (c *Cache) fresh(entry *cacheEntry, now time.Time) bool {
+	return !c.Refresh && now.Sub(entry.Fetched) < c.TTL
+}
+
+// Removes all cached responses
+func (c *Cache) Clear() error {
+	err := os.RemoveAll(c.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Backups end in ".bak", so that package managers ignore them (e.g. APT in sources.list.d)
+	BACKUP_SUFFIX = ".bak"
+	// Timestamp format in backup names (sorts chronologically)
+	BACKUP_TIME_FORMAT = "20060102T150405.000000000Z"
+	// Suffix of the (empty) backup of a file that did not exist, before BACKUP_SUFFIX
+	CREATED_SUFFIX = ".created"
+)
+
+// Writes data to a file atomically: data is written to a temporary file in the same directory,
+// which is then renamed over the file. Missing parent directories are created.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".gomirror-*")
+	if err != nil {
+		return err
+	}
+	// Remove temporary file on failure (no-op after rename)
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Returns the timestamped backup name of a file
+func BackupName(filename string, t time.Time) string {
+	return fmt.Sprintf("%v.gomirror-%v%v", filename, t.UTC().Format(BACKUP_TIME_FORMAT), BACKUP_SUFFIX)
+}
+
+// Returns the name of the backup that records that a file did not exist
+func CreatedName(filename string, t time.Time) string {
+	return fmt.Sprintf("%v.gomirror-%v%v%v", filename, t.UTC().Format(BACKUP_TIME_FORMAT), CREATED_SUFFIX, BACKUP_SUFFIX)
+}
+
+// Reports whether a backup records that the file did not exist (see CreatedName)
+func IsCreated(backup string) bool {
+	return strings.HasSuffix(backup, CREATED_SUFFIX+BACKUP_SUFFIX)
+}
+
+// Returns the backups of a file, oldest first
+func Backups(filename string) ([]string, error) {
+	backups, err := filepath.Glob(filename + ".gomirror-*" + BACKUP_SUFFIX)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// Replaces the content of a file atomically, keeping a timestamped backup of the current content.
+// If the file did not exist, an empty backup records that it was created (see CreatedName), so that restoring removes it.
+// Returns the backup name (empty if the file did not exist).
+func ReplaceFile(filename string, data []byte) (string, error) {
+	perm := os.FileMode(0644)
+	backup := ""
+	current, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		info, err := os.Stat(filename)
+		if err != nil {
+			return "", err
+		}
+		perm = info.Mode().Perm()
+		backup = BackupName(filename, time.Now())
+		if err := WriteFileAtomic(backup, current, perm); err != nil {
+			return "", fmt.Errorf("can not back up %v: %w", filename, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err := WriteFileAtomic(CreatedName(filename, time.Now()), nil, perm); err != nil {
+			return "", fmt.Errorf("can not record creation of %v: %w", filename, err)
+		}
+	default:
+		return "", err
+	}
+	if err := WriteFileAtomic(filename, data, perm); err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
+
+// Restores a file atomically from its latest backup, or removes it if it was created (see IsCreated).
+// The backup is removed after restoring. Returns the backup name.
+func RestoreFile(filename string) (string, error) {
+	backups, err := Backups(filename)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no backup found for %v", filename)
+	}
+	backup := backups[len(backups)-1]
+	if IsCreated(backup) {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		return backup, os.Remove(backup)
+	}
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(backup)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(filename, data, info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return backup, os.Remove(backup)
+}
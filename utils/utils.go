@@ -1,32 +1,93 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/pariz/gountries"
 )
 
 // Generic function that creates an HTTP GET request and returns the body.
 func GetRequest(URL string) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", URL, nil)
+	return GetRequestContext(context.Background(), URL)
+}
+
+// Same as GetRequest, but the request is aborted when the context is done.
+// If the context has a cache (see WithCache), cached responses are used and revalidated.
+func GetRequestContext(ctx context.Context, URL string) ([]byte, error) {
+	cache := CacheFromContext(ctx)
+	if cache == nil {
+		body, _, err := getRequest(ctx, URL, nil)
+		return body, err
+	}
+	entry, cached := cache.load(URL)
+	if entry != nil && cache.fresh(entry, time.Now()) {
+		return cached, nil
+	}
+	body, newEntry, err := getRequest(ctx, URL, entry)
 	if err != nil {
+		// Use the outdated response, if the request failed (but was not aborted)
+		if entry != nil && ctx.Err() == nil {
+			log.Printf("Error: Can not revalidate cached %v, using cached response from %v: %v", URL, entry.Fetched.Format(time.RFC3339), err)
+			return cached, nil
+		}
 		return nil, err
 	}
+	// Not modified
+	if body == nil {
+		body = cached
+	}
+	if err := cache.store(newEntry, body); err != nil {
+		log.Printf("Error: Can not cache %v: %v", URL, err)
+	}
+	return body, nil
+}
+
+// Makes an HTTP GET request, conditional if there is a cached response.
+// Returns a nil body if the cached response is not modified, and the cache entry of the response.
+func getRequest(ctx context.Context, URL string, entry *cacheEntry) ([]byte, *cacheEntry, error) {
+	client := &http.Client{}
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry != nil {
+		if len(entry.ETag) != 0 {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if len(entry.LastModified) != 0 {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		updated := *entry
+		updated.Fetched = time.Now()
+		return nil, &updated, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected HTTP status: %v", resp.Status)
+	}
 	bodyText, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return bodyText, nil
+	return bodyText, &cacheEntry{
+		URL:          URL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}, nil
 }
 
 // Uses geo-location API services to locate the user from its external IP
@@ -76,6 +137,16 @@ func GetCountryCode(country string) string {
 	return countryCode.Alpha2
 }
 
+// Returns the name of the country with the given 2 letter country code (empty if unknown).
+func GetCountryName(countryCode string) string {
+	query := gountries.New()
+	country, err := query.FindCountryByAlpha(countryCode)
+	if err != nil {
+		return ""
+	}
+	return CorrectCountryName(country.Name.Common)
+}
+
 // Returns the correct country for name for some country alternative naming.
 func CorrectCountryName(country string) string {
 	switch country {
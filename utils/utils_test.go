@@ -1,6 +1,15 @@
 package utils
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestGetCountry(t *testing.T) {
 	expectedCountry := "Greece"
@@ -9,3 +18,128 @@ func TestGetCountry(t *testing.T) {
 		t.Fatalf("Expected: %v, Got: %v", expectedCountry, country)
 	}
 }
+
+func TestReplaceAndRestoreFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "etc/apt/sources.list")
+
+	// New file, no backup
+	backup, err := ReplaceFile(filename, []byte("first\n"))
+	if err != nil || len(backup) != 0 {
+		t.Fatalf("Expected no backup and no error, Got: %v, %v", backup, err)
+	}
+	if err := os.Chmod(filename, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing file, backup keeps content and permissions
+	backup, err = ReplaceFile(filename, []byte("second\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, backup, "first\n", 0600)
+	assertFile(t, filename, "second\n", 0600)
+
+	// Restore from latest backup, which is then removed
+	restored, err := RestoreFile(filename)
+	if err != nil || restored != backup {
+		t.Fatalf("Expected: %v, Got: %v, %v", backup, restored, err)
+	}
+	assertFile(t, filename, "first\n", 0600)
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Fatalf("Expected backup to be removed, Got: %v", err)
+	}
+
+	// The file did not exist before the first replace, so it is removed
+	restored, err = RestoreFile(filename)
+	if err != nil || !IsCreated(restored) {
+		t.Fatalf("Expected creation backup, Got: %v, %v", restored, err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("Expected file to be removed, Got: %v", err)
+	}
+	if _, err := os.Stat(restored); !os.IsNotExist(err) {
+		t.Fatalf("Expected backup to be removed, Got: %v", err)
+	}
+	if _, err := RestoreFile(filename); err == nil {
+		t.Fatalf("Expected error when no backup exists")
+	}
+}
+
+func assertFile(t *testing.T, filename string, content string, perm os.FileMode) {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Fatalf("Expected: %q, Got: %q", content, string(data))
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != perm {
+		t.Fatalf("Expected: %v, Got: %v", perm, info.Mode().Perm())
+	}
+}
+
+func TestGetRequestCache(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("mirrors"))
+	}))
+	defer server.Close()
+
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	ctx := WithCache(context.Background(), cache)
+	get := func() string {
+		body, err := GetRequestContext(ctx, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	// First request is cached, and used within the TTL
+	if body := get(); body != "mirrors" {
+		t.Fatalf("Expected: mirrors, Got: %v", body)
+	}
+	if body := get(); body != "mirrors" || atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("Expected cached response without request, Got: %v, %v requests", body, atomic.LoadInt32(&requests))
+	}
+
+	// Revalidated with a conditional request
+	cache.Refresh = true
+	if body := get(); body != "mirrors" || atomic.LoadInt32(&requests) != 2 || atomic.LoadInt32(&notModified) != 1 {
+		t.Fatalf("Expected not modified response, Got: %v, %v requests, %v not modified", body, atomic.LoadInt32(&requests), atomic.LoadInt32(&notModified))
+	}
+
+	// Cached response is used if the request fails
+	server.Close()
+	if body := get(); body != "mirrors" {
+		t.Fatalf("Expected cached response, Got: %v", body)
+	}
+
+	// Requests without cache fail
+	if _, err := GetRequest(server.URL); err == nil {
+		t.Fatalf("Expected error without cache")
+	}
+
+	// Cleared cache can not be used either
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetRequestContext(ctx, server.URL); err == nil {
+		t.Fatalf("Expected error with cleared cache")
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Expected no error clearing an empty cache, Got: %v", err)
+	}
+}